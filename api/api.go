@@ -14,7 +14,10 @@
 
 package api
 
-import ()
+import (
+	"fmt"
+	"time"
+)
 
 const (
 	RunStateStart = "running"
@@ -25,6 +28,11 @@ const (
 	RunStateReset = "reset"
 )
 
+/*
+ Timestamp layout used in API responses, e.g. "2016/12/13 11:29:56 -0800".
+*/
+const SkytapTimeFormat = "2006/01/02 15:04:05 -0700"
+
 func isOkStatus(code int) bool {
 	codes := map[int]bool{
 		200: true,
@@ -45,3 +53,20 @@ func isOkStatus(code int) bool {
 func isBusy(code int) bool {
 	return code == 423
 }
+
+/*
+ Parse a timestamp from an API response. Accepts SkytapTimeFormat and RFC 3339, empty strings give the zero time.
+*/
+func parseSkytapTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(SkytapTimeFormat, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("Unable to parse timestamp '%s'", value)
+	}
+	return t, nil
+}
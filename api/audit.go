@@ -0,0 +1,286 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AuditReportPath = "auditing/exports"
+)
+
+/*
+ Request body for audit report commands. Empty filters match everything.
+*/
+type AuditReportRequest struct {
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	UserId       string `json:"user_id,omitempty"`
+	Category     string `json:"category,omitempty"`
+	Activity     string `json:"activity,omitempty"`
+	ResourceType string `json:"resource_type,omitempty"`
+	ResourceId   string `json:"resource_id,omitempty"`
+	Utc          bool   `json:"utc"`
+}
+
+/*
+ Audit report, which is generated asynchronously. Poll with WaitUntilReady before reading events.
+*/
+type AuditReport struct {
+	Id    string `json:"id"`
+	Url   string `json:"url,omitempty"`
+	Ready bool   `json:"ready"`
+}
+
+/*
+ Single audited action.
+*/
+type AuditEvent struct {
+	Id        string
+	Timestamp time.Time
+	Actor     AuditActor
+	Category  string
+	Action    string
+	Target    AuditTarget
+	Details   string
+}
+
+/*
+ User who performed an audited action.
+*/
+type AuditActor struct {
+	Id        string `json:"id"`
+	LoginName string `json:"login_name"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	IpAddress string `json:"ip_address"`
+}
+
+/*
+ Resource an audited action was performed on.
+*/
+type AuditTarget struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+func (e *AuditEvent) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		Id        string      `json:"id"`
+		Date      string      `json:"date"`
+		User      AuditActor  `json:"user"`
+		IpAddress string      `json:"ip_address"`
+		Category  string      `json:"category"`
+		Activity  string      `json:"activity"`
+		Target    AuditTarget `json:"target"`
+		Details   string      `json:"details"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	timestamp, err := parseSkytapTime(raw.Date)
+	if err != nil {
+		return err
+	}
+	if raw.User.IpAddress == "" {
+		raw.User.IpAddress = raw.IpAddress
+	}
+
+	*e = AuditEvent{
+		Id:        raw.Id,
+		Timestamp: timestamp,
+		Actor:     raw.User,
+		Category:  raw.Category,
+		Action:    raw.Activity,
+		Target:    raw.Target,
+		Details:   raw.Details,
+	}
+	return nil
+}
+
+/*
+ Only include actions performed in the given period, see UsageReportRequest.SetRange.
+*/
+func (r *AuditReportRequest) SetRange(start time.Time, end time.Time) {
+	r.StartDate, r.EndDate = reportRange(start, end)
+	r.Utc = true
+}
+
+func auditReportIdPath(reportId string) string {
+	return fmt.Sprintf("%s/%s.json", AuditReportPath, reportId)
+}
+
+/*
+ Request generation of an audit report. The returned report is usually not ready yet.
+*/
+func RequestAuditReport(client SkytapClient, request *AuditReportRequest) (*AuditReport, error) {
+	log.WithFields(log.Fields{"request": request}).Info("Requesting audit report")
+
+	if request.StartDate == "" || request.EndDate == "" {
+		return nil, errors.New("Audit report requires a start and end date")
+	}
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(AuditReportPath + ".json").BodyJSON(request)
+	}
+
+	report := &AuditReport{}
+	_, err := RunSkytapRequest(client, false, report, createReq)
+	return report, err
+}
+
+/*
+ Return an audit report by id.
+*/
+func GetAuditReport(client SkytapClient, reportId string) (*AuditReport, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(auditReportIdPath(reportId))
+	}
+
+	report := &AuditReport{}
+	_, err := RunSkytapRequest(client, false, report, getReq)
+	return report, err
+}
+
+func (r *AuditReport) RunstateStr() string {
+	return reportRunstate(r.Ready)
+}
+
+func (r *AuditReport) Refresh(client SkytapClient) (RunstateAwareResource, error) {
+	return GetAuditReport(client, r.Id)
+}
+
+/*
+ Wait until the audit events can be read.
+*/
+func (r *AuditReport) WaitUntilReady(client SkytapClient) (*AuditReport, error) {
+	ready, err := waitUntilReportReady(client, r)
+	return ready.(*AuditReport), err
+}
+
+/*
+ Open the report results for reading event by event. The report must be ready, and the reader must be closed.
+*/
+func (r *AuditReport) Events(client SkytapClient) (*AuditEventReader, error) {
+	if !r.Ready || r.Url == "" {
+		return nil, fmt.Errorf("Audit report %s is not ready for download", r.Id)
+	}
+	body, err := OpenSkytapResource(client, r.Url)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuditEventReader(body), nil
+}
+
+/*
+ Streams audit events from a JSON array or from concatenated JSON objects (one per line).
+*/
+type AuditEventReader struct {
+	source   io.Reader
+	buffered *bufio.Reader
+	decoder  *json.Decoder
+	inArray  bool
+	started  bool
+}
+
+/*
+ Create a reader for audit events. If source is an io.Closer, Close closes it.
+*/
+func NewAuditEventReader(source io.Reader) *AuditEventReader {
+	buffered := bufio.NewReader(source)
+	return &AuditEventReader{source: source, buffered: buffered, decoder: json.NewDecoder(buffered)}
+}
+
+/*
+ Return the next event, or io.EOF after the last one.
+*/
+func (ar *AuditEventReader) Next() (*AuditEvent, error) {
+	if !ar.started {
+		ar.started = true
+		if err := ar.readArrayStart(); err != nil {
+			return nil, err
+		}
+	}
+	if ar.inArray && !ar.decoder.More() {
+		return nil, io.EOF
+	}
+
+	event := &AuditEvent{}
+	if err := ar.decoder.Decode(event); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("Unable to parse audit event: %s", err)
+	}
+	return event, nil
+}
+
+/*
+ Call fn for each remaining event, stopping at the first error.
+*/
+func (ar *AuditEventReader) ForEach(fn func(*AuditEvent) error) error {
+	for {
+		event, err := ar.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+func (ar *AuditEventReader) Close() error {
+	if closer, ok := ar.source.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Consume the opening bracket if the events are wrapped in an array.
+func (ar *AuditEventReader) readArrayStart() error {
+	for {
+		b, err := ar.buffered.ReadByte()
+		if err != nil {
+			return err
+		}
+		if unicode.IsSpace(rune(b)) {
+			continue
+		}
+		if err = ar.buffered.UnreadByte(); err != nil || b != '[' {
+			return err
+		}
+		// Let the decoder consume the bracket, so it handles the separators between events.
+		_, err = ar.decoder.Token()
+		ar.inArray = err == nil
+		return err
+	}
+}
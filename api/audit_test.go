@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditReport(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/auditing/exports.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"start_date":"2030/01/01 00:00:00","end_date":"2030/01/02 00:00:00","category":"Environment","utc":true}`,
+			strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"7","ready":false}`)
+	})
+
+	request := &AuditReportRequest{Category: "Environment"}
+	request.SetRange(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	report, err := RequestAuditReport(client, request)
+	require.NoError(t, err, "Error requesting audit report")
+	_, err = report.Events(client)
+	require.Error(t, err, "Should not read a report that isn't ready")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auditing/exports/7.json":
+			fmt.Fprintf(w, `{"id":"7","ready":true,"url":"%s/download/7.json"}`, server.URL)
+		case "/download/7.json":
+			fmt.Fprint(w, `[
+				{"id":"1","date":"2016/12/13 11:29:56 -0800","user":{"id":"3","login_name":"jane.doe@example.com"},
+				 "ip_address":"10.0.0.1","category":"Environment","activity":"Created","target":{"type":"Environment","id":"42"}},
+				{"id":"2","date":"2016/12/13 11:30:01 -0800","user":{"id":"3"},"category":"Environment","activity":"Deleted"}
+			]`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	})
+
+	report, err = report.WaitUntilReady(client)
+	require.NoError(t, err, "Error waiting for audit report")
+
+	events, err := report.Events(client)
+	require.NoError(t, err, "Error opening audit report")
+	defer events.Close()
+
+	collected := []*AuditEvent{}
+	err = events.ForEach(func(event *AuditEvent) error {
+		collected = append(collected, event)
+		return nil
+	})
+	require.NoError(t, err, "Error reading audit events")
+	require.Len(t, collected, 2)
+	require.Equal(t, "Created", collected[0].Action)
+	require.Equal(t, "jane.doe@example.com", collected[0].Actor.LoginName)
+	require.Equal(t, "10.0.0.1", collected[0].Actor.IpAddress)
+	require.Equal(t, "42", collected[0].Target.Id)
+	require.Equal(t, time.Date(2016, 12, 13, 19, 29, 56, 0, time.UTC), collected[0].Timestamp.UTC())
+}
+
+func TestAuditEventReaderLines(t *testing.T) {
+	events := NewAuditEventReader(strings.NewReader(
+		`{"id":"1","date":"2016-12-13T11:29:56-08:00","activity":"Started"}` + "\n" + `{"id":"2","activity":"Stopped"}` + "\n"))
+
+	event, err := events.Next()
+	require.NoError(t, err, "Error reading first event")
+	require.Equal(t, "Started", event.Action)
+	event, err = events.Next()
+	require.NoError(t, err, "Error reading second event")
+	require.Equal(t, "2", event.Id)
+	_, err = events.Next()
+	require.Equal(t, io.EOF, err)
+
+	_, err = NewAuditEventReader(strings.NewReader(`[{"id":"1","date":"yesterday"}]`)).Next()
+	require.Error(t, err, "Should fail on unparseable date")
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+ Options for VirtualMachine.Clone. All fields are optional.
+*/
+type CloneOptions struct {
+	// Name of the clone, defaults to the name Skytap assigns
+	Name string
+	// New hostnames for the clone's interfaces, keyed by the hostname of the source interface
+	Hostnames map[string]string
+	// Wait until the clone is stopped or running before returning
+	Wait bool
+}
+
+/*
+ Copy this VM into an existing environment, which may be the VM's own environment.
+
+ The VM must belong to an environment. Returns the clone, renamed and with interfaces renamed as requested.
+*/
+func (vm *VirtualMachine) Clone(client SkytapClient, targetEnvId string, opts CloneOptions) (*VirtualMachine, error) {
+	sourceEnvId := idFromUrl(vm.EnvironmentUrl)
+	if sourceEnvId == "" {
+		return nil, fmt.Errorf("VM %s is not in an environment", vm.Id)
+	}
+	log.WithFields(log.Fields{"vmId": vm.Id, "sourceEnvId": sourceEnvId, "targetEnvId": targetEnvId}).Info("Cloning VM")
+
+	target, err := GetEnvironment(client, targetEnvId)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, targetVm := range target.Vms {
+		existing[targetVm.Id] = true
+	}
+
+	merged, err := target.MergeEnvironmentVirtualMachine(client, sourceEnvId, vm.Id)
+	if err != nil {
+		return nil, err
+	}
+	var clone *VirtualMachine
+	for _, mergedVm := range merged.Vms {
+		if !existing[mergedVm.Id] {
+			clone = mergedVm
+			break
+		}
+	}
+	if clone == nil {
+		return nil, fmt.Errorf("Unable to find the clone of VM %s in environment %s", vm.Id, targetEnvId)
+	}
+
+	renames := map[string]string{}
+	for i, nic := range clone.Interfaces {
+		if i < len(vm.Interfaces) {
+			if hostname, ok := opts.Hostnames[vm.Interfaces[i].Hostname]; ok && hostname != nic.Hostname {
+				renames[nic.Id] = hostname
+			}
+		}
+	}
+
+	if opts.Name != "" || len(renames) > 0 {
+		if clone, err = clone.WaitUntilReady(client); err != nil {
+			return clone, err
+		}
+	}
+	if opts.Name != "" {
+		if _, err = clone.SetName(client, opts.Name); err != nil {
+			return clone, err
+		}
+	}
+	for nicId, hostname := range renames {
+		if _, err = clone.RenameNetworkInterface(client, targetEnvId, nicId, hostname); err != nil {
+			return clone, err
+		}
+	}
+
+	if opts.Wait {
+		return clone.WaitUntilReady(client)
+	}
+	if opts.Name != "" || len(renames) > 0 {
+		return GetVirtualMachine(client, clone.Id)
+	}
+	return clone, nil
+}
+
+// Last path segment of a resource URL, e.g. the environment id of a configuration_url.
+func idFromUrl(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneVm(t *testing.T) {
+	envJson := readJson(t, "testdata/environment-1.json")
+	vmJson := readJson(t, "testdata/vm-1001.json")
+	cloneJson := strings.Replace(vmJson, `"id": "1001"`, `"id": "2002"`, 1)
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	source := &VirtualMachine{}
+	require.NoError(t, json.Unmarshal([]byte(vmJson), source))
+	renamed := false
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/configurations/1.json":
+			fmt.Fprintln(w, envJson)
+		case r.Method == "PUT" && r.URL.Path == "/configurations/1.json":
+			body, _ := ioutil.ReadAll(r.Body)
+			require.Equal(t, `{"merge_configuration":"1","vm_ids":["1001"]}`, strings.TrimSpace(string(body)))
+			env := &Environment{}
+			require.NoError(t, json.Unmarshal([]byte(envJson), env))
+			fmt.Fprintf(w, `{"id":"1","vms":[{"id":"%s"},%s]}`, env.Vms[0].Id, cloneJson)
+		case r.Method == "GET" && r.URL.Path == "/vms/2002":
+			fmt.Fprintln(w, cloneJson)
+		case r.Method == "PUT" && r.URL.Path == "/vms/2002.json":
+			require.Equal(t, "clone", r.URL.Query().Get("name"))
+			fmt.Fprintln(w, cloneJson)
+		case r.Method == "PUT" && r.URL.Path == "/configurations/1/vms/2002/interfaces/nic-5971736-13548234-0.json":
+			body, _ := ioutil.ReadAll(r.Body)
+			require.Equal(t, `{"hostname":"host-clone"}`, strings.TrimSpace(string(body)))
+			renamed = true
+			fmt.Fprintln(w, `{}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	clone, err := source.Clone(client, "1", CloneOptions{
+		Name:      "clone",
+		Hostnames: map[string]string{source.Interfaces[0].Hostname: "host-clone"},
+		Wait:      true,
+	})
+	require.NoError(t, err, "Error cloning VM")
+	require.Equal(t, "2002", clone.Id)
+	require.True(t, renamed, "Should rename the clone's interface")
+
+	_, err = (&VirtualMachine{Id: "3"}).Clone(client, "1", CloneOptions{})
+	require.Error(t, err, "Should require the VM to be in an environment")
+}
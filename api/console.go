@@ -0,0 +1,214 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"crypto/des"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DesktopSessionPath = "desktop_sessions"
+
+	ConsoleProtocolBrowser = "browser"
+	ConsoleProtocolVnc     = "vnc"
+	ConsoleProtocolRdp     = "rdp"
+
+	RdpPort = 3389
+	VncPort = 5900
+)
+
+/*
+ Console session of a VM. For browser sessions only Url is set, VNC and RDP sessions carry connection details.
+*/
+type DesktopSession struct {
+	Id        string
+	Protocol  string
+	Url       string
+	Host      string
+	Port      int
+	Password  string
+	ExpiresAt time.Time
+}
+
+/*
+ Request body for desktop session create commands.
+*/
+type DesktopSessionBody struct {
+	Protocol string `json:"protocol"`
+}
+
+func (d *DesktopSession) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		Id        string `json:"id"`
+		Protocol  string `json:"protocol"`
+		Url       string `json:"url"`
+		Host      string `json:"host"`
+		Port      int    `json:"port"`
+		Password  string `json:"password"`
+		ExpiresAt string `json:"expires_at"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	expiresAt, err := parseSkytapTime(raw.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	*d = DesktopSession{
+		Id:        raw.Id,
+		Protocol:  raw.Protocol,
+		Url:       raw.Url,
+		Host:      raw.Host,
+		Port:      raw.Port,
+		Password:  raw.Password,
+		ExpiresAt: expiresAt,
+	}
+	return nil
+}
+
+/*
+ Returns true if the session has an expiry time that has passed.
+*/
+func (d *DesktopSession) Expired() bool {
+	return !d.ExpiresAt.IsZero() && time.Now().After(d.ExpiresAt)
+}
+
+/*
+ Return a connection file for the session, .rdp for RDP sessions and .vnc for VNC sessions.
+*/
+func (d *DesktopSession) ConnectionFile() ([]byte, error) {
+	switch d.Protocol {
+	case ConsoleProtocolRdp:
+		return RdpFile(d.Host, d.Port, ""), nil
+	case ConsoleProtocolVnc:
+		return VncFile(d.Host, d.Port, d.Password), nil
+	}
+	return nil, fmt.Errorf("No connection file for %s sessions", d.Protocol)
+}
+
+func desktopSessionsPath(vmId string) string {
+	return fmt.Sprintf("%s/%s/%s.json", VmPath, vmId, DesktopSessionPath)
+}
+
+/*
+ Create a console session for the VM, using one of the ConsoleProtocol* protocols. The VM must be running.
+*/
+func (vm *VirtualMachine) CreateDesktopSession(client SkytapClient, protocol string) (*DesktopSession, error) {
+	log.WithFields(log.Fields{"vmId": vm.Id, "protocol": protocol}).Info("Creating desktop session")
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(desktopSessionsPath(vm.Id)).BodyJSON(&DesktopSessionBody{Protocol: protocol})
+	}
+
+	session := &DesktopSession{}
+	_, err := RunSkytapRequest(client, false, session, createReq)
+	return session, err
+}
+
+/*
+ Return a URL that opens the VM console in a browser, and the time it expires.
+*/
+func (vm *VirtualMachine) GetConsoleURL(client SkytapClient) (string, time.Time, error) {
+	session, err := vm.CreateDesktopSession(client, ConsoleProtocolBrowser)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return session.Url, session.ExpiresAt, nil
+}
+
+/*
+ Return the host and port to reach an internal port of this interface from outside the environment: the published
+ service for the port if there is one, otherwise the first public IP, otherwise the interface IP.
+*/
+func (nic *NetworkInterface) ExternalEndpoint(port int) (string, int) {
+	for _, service := range nic.PublishedServices {
+		if service.InternalPort == port && service.ExternalIp != "" {
+			return service.ExternalIp, service.ExternalPort
+		}
+	}
+	if len(nic.PublicIps) > 0 {
+		return nic.PublicIps[0].Address, port
+	}
+	return nic.Ip, port
+}
+
+/*
+ Return an .rdp file connecting to this interface, see ExternalEndpoint.
+*/
+func (nic *NetworkInterface) RdpFile(username string) []byte {
+	host, port := nic.ExternalEndpoint(RdpPort)
+	return RdpFile(host, port, username)
+}
+
+/*
+ Return a .vnc file connecting to the VNC server on this interface, see ExternalEndpoint.
+*/
+func (nic *NetworkInterface) VncFile(password string) []byte {
+	host, port := nic.ExternalEndpoint(VncPort)
+	return VncFile(host, port, password)
+}
+
+/*
+ Return the contents of an .rdp file for the given endpoint. The username is optional.
+*/
+func RdpFile(host string, port int, username string) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "full address:s:%s:%d\r\n", host, port)
+	if username != "" {
+		fmt.Fprintf(buf, "username:s:%s\r\n", username)
+	}
+	buf.WriteString("prompt for credentials:i:1\r\n")
+	buf.WriteString("screen mode id:i:2\r\n")
+	return buf.Bytes()
+}
+
+/*
+ Return the contents of a .vnc file for the given endpoint. The password is optional; it is stored obfuscated the way
+ VNC viewers expect, which hides it from casual reading but is not encryption.
+*/
+func VncFile(host string, port int, password string) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("[connection]\n")
+	fmt.Fprintf(buf, "host=%s\n", host)
+	fmt.Fprintf(buf, "port=%d\n", port)
+	if password != "" {
+		fmt.Fprintf(buf, "password=%s\n", vncObfuscatePassword(password))
+	}
+	return buf.Bytes()
+}
+
+// Fixed DES key VNC uses to obfuscate stored passwords, with the bits of each byte reversed as VNC does.
+var vncPasswordKey = []byte{0xe8, 0x4a, 0xd6, 0x60, 0xc4, 0x72, 0x1a, 0xe0}
+
+// Hex encoded VNC obfuscation of a password. VNC only uses the first 8 characters of a password.
+func vncObfuscatePassword(password string) string {
+	// Only fails for keys of the wrong size
+	block, _ := des.NewCipher(vncPasswordKey)
+	plain := make([]byte, des.BlockSize)
+	copy(plain, password)
+	obfuscated := make([]byte, des.BlockSize)
+	block.Encrypt(obfuscated, plain)
+	return hex.EncodeToString(obfuscated)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConsoleURL(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/vms/1001/desktop_sessions.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"protocol":"browser"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"1","protocol":"browser","url":"https://cloud.skytap.com/vms/abc/desktops","expires_at":"2030/01/01 10:00:00 -0800"}`)
+	})
+
+	vm := &VirtualMachine{Id: "1001"}
+	url, expiresAt, err := vm.GetConsoleURL(client)
+	require.NoError(t, err, "Error getting console URL")
+	require.Equal(t, "https://cloud.skytap.com/vms/abc/desktops", url)
+	require.Equal(t, time.Date(2030, 1, 1, 18, 0, 0, 0, time.UTC), expiresAt.UTC())
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"id":"2","protocol":"vnc","host":"vnc.skytap.com","port":15900,"password":"secret"}`)
+	})
+	session, err := vm.CreateDesktopSession(client, ConsoleProtocolVnc)
+	require.NoError(t, err, "Error creating VNC session")
+	require.False(t, session.Expired())
+	file, err := session.ConnectionFile()
+	require.NoError(t, err)
+	require.Equal(t, "[connection]\nhost=vnc.skytap.com\nport=15900\npassword=2e2dbf576eb06c9e\n", string(file))
+	require.Equal(t, "dbd83cfd727a1458", vncObfuscatePassword("password"))
+}
+
+func TestNetworkInterfaceRdpFile(t *testing.T) {
+	nic := &NetworkInterface{Ip: "10.0.0.1"}
+	require.Contains(t, string(nic.RdpFile("admin")), "full address:s:10.0.0.1:3389\r\nusername:s:admin\r\n")
+
+	nic.PublishedServices = []PublishedService{{InternalPort: 3389, ExternalIp: "services-uswest.skytap.com", ExternalPort: 17001}}
+	host, port := nic.ExternalEndpoint(RdpPort)
+	require.Equal(t, "services-uswest.skytap.com", host)
+	require.Equal(t, 17001, port)
+	require.Contains(t, string(nic.VncFile("")), "port=5900\n")
+}
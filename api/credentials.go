@@ -0,0 +1,152 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+ Structured form of a VM credential, which Skytap stores as free text like "username / password".
+*/
+type Credential struct {
+	Id       string
+	Username string
+	Password string
+	// Any lines after the first
+	Notes string
+}
+
+/*
+ Request body for credential create and update commands.
+*/
+type CredentialBody struct {
+	Text string `json:"text"`
+}
+
+/*
+ Parse credential text. The first line holds the username and password, separated by the first "/", which may be
+ surrounded by spaces, so passwords may contain slashes. Following lines are returned as notes.
+
+ Errors never include the text, as it may hold a password.
+*/
+func ParseCredential(text string) (*Credential, error) {
+	lines := strings.SplitN(strings.Replace(strings.TrimSpace(text), "\r\n", "\n", -1), "\n", 2)
+	first := lines[0]
+
+	// The spaced separator only wins if the username has no slash, otherwise "a/b / c" would split inside the password
+	separator := strings.Index(first, " / ")
+	separatorLen := 3
+	if separator < 0 || strings.Contains(first[:separator], "/") {
+		separator = strings.Index(first, "/")
+		separatorLen = 1
+	}
+	if separator < 0 {
+		return nil, errors.New("Incorrect parts in credential string, expected username/password")
+	}
+
+	credential := &Credential{
+		Username: strings.TrimSpace(first[:separator]),
+		Password: strings.TrimSpace(first[separator+separatorLen:]),
+	}
+	if credential.Username == "" {
+		return nil, errors.New("Missing username in credential string")
+	}
+	if len(lines) > 1 {
+		credential.Notes = strings.TrimSpace(lines[1])
+	}
+	return credential, nil
+}
+
+/*
+ Return the credential as text in the format understood by ParseCredential.
+*/
+func (c *Credential) Text() string {
+	text := c.Username + " / " + c.Password
+	if c.Notes != "" {
+		text += "\n" + c.Notes
+	}
+	return text
+}
+
+/*
+ Parse the credential text, see ParseCredential.
+*/
+func (c *VmCredential) Parse() (*Credential, error) {
+	credential, err := ParseCredential(c.Text)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse credential %s: %s", c.Id, err)
+	}
+	credential.Id = c.Id
+	return credential, nil
+}
+
+// Credential texts hold passwords, only the id is logged.
+func (c *VmCredential) redactedForLog() interface{} {
+	return &VmCredential{Id: c.Id, Text: "[redacted]"}
+}
+
+func vmCredentialIdPath(vmId string, credentialId string) string {
+	return fmt.Sprintf("%s/%s/credentials/%s.json", VmPath, vmId, credentialId)
+}
+
+/*
+ Add a credential to the VM.
+*/
+func (vm *VirtualMachine) CreateCredential(client SkytapClient, credential *Credential) (*VmCredential, error) {
+	log.WithFields(log.Fields{"vmId": vm.Id, "username": credential.Username}).Info("Creating VM credential")
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(vmCredentialPath(vm.Id)).BodyJSON(&CredentialBody{Text: credential.Text()})
+	}
+
+	created := &VmCredential{}
+	_, err := RunSkytapRequest(client, false, created, createReq)
+	return created, err
+}
+
+/*
+ Replace an existing credential of the VM.
+*/
+func (vm *VirtualMachine) UpdateCredential(client SkytapClient, credentialId string, credential *Credential) (*VmCredential, error) {
+	log.WithFields(log.Fields{"vmId": vm.Id, "credentialId": credentialId}).Info("Updating VM credential")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(vmCredentialIdPath(vm.Id, credentialId)).BodyJSON(&CredentialBody{Text: credential.Text()})
+	}
+
+	updated := &VmCredential{}
+	_, err := RunSkytapRequest(client, false, updated, updateReq)
+	return updated, err
+}
+
+/*
+ Remove a credential from the VM.
+*/
+func (vm *VirtualMachine) DeleteCredential(client SkytapClient, credentialId string) error {
+	log.WithFields(log.Fields{"vmId": vm.Id, "credentialId": credentialId}).Info("Deleting VM credential")
+
+	deleteReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(vmCredentialIdPath(vm.Id, credentialId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, deleteReq)
+	return err
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCredential(t *testing.T) {
+	credential, err := ParseCredential("  admin /  pa/ss/word \nDomain admin, rotate monthly\n")
+	require.NoError(t, err)
+	require.Equal(t, "admin", credential.Username)
+	require.Equal(t, "pa/ss/word", credential.Password)
+	require.Equal(t, "Domain admin, rotate monthly", credential.Notes)
+
+	credential, err = ParseCredential("root/se/cret")
+	require.NoError(t, err)
+	require.Equal(t, "root", credential.Username)
+	require.Equal(t, "se/cret", credential.Password)
+
+	credential, err = ParseCredential("admin/pa / ss")
+	require.NoError(t, err)
+	require.Equal(t, "admin", credential.Username)
+	require.Equal(t, "pa / ss", credential.Password)
+
+	_, err = ParseCredential("no separator")
+	require.Error(t, err, "Should require a separator")
+	_, err = ParseCredential(" / hunter2")
+	require.Error(t, err, "Should require a username")
+	require.NotContains(t, err.Error(), "hunter2", "Should not leak the password")
+
+	_, err = (&VmCredential{Id: "7", Text: "secretpassword"}).Parse()
+	require.EqualError(t, err, "Unable to parse credential 7: Incorrect parts in credential string, expected username/password")
+
+	vmCredential := &VmCredential{Id: "1", Text: "user / a/b"}
+	password, err := vmCredential.Password()
+	require.NoError(t, err)
+	require.Equal(t, "a/b", password)
+}
+
+func TestCredentialCrud(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	vm := &VirtualMachine{Id: "1001"}
+	credential := &Credential{Username: "admin", Password: "pa/ss", Notes: "test account"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/vms/1001/credentials.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"text":"admin / pa/ss\ntest account"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"5","text":"admin / pa/ss\ntest account"}`)
+	})
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+	created, err := vm.CreateCredential(client, credential)
+	require.NoError(t, err, "Error creating credential")
+	require.NotContains(t, logs.String(), "pa/ss", "Should not log the password")
+	parsed, err := created.Parse()
+	require.NoError(t, err)
+	require.Equal(t, "5", parsed.Id)
+	require.Equal(t, "test account", parsed.Notes)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/vms/1001/credentials/5.json", r.URL.Path)
+		fmt.Fprintln(w, `{"id":"5","text":"admin / new"}`)
+	})
+	credential.Password = "new"
+	_, err = vm.UpdateCredential(client, "5", credential)
+	require.NoError(t, err, "Error updating credential")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/vms/1001/credentials/5.json", r.URL.Path)
+	})
+	require.NoError(t, vm.DeleteCredential(client, "5"), "Error deleting credential")
+}
@@ -0,0 +1,71 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+/*
+ Disks of a single VM, with their total size in MB.
+*/
+type VmDiskInventory struct {
+	VmId      string
+	VmName    string
+	Disks     []Disk
+	TotalSize int
+}
+
+/*
+ Disks of all VMs in an environment, with the total size in MB.
+*/
+type EnvironmentDiskInventory struct {
+	EnvironmentId string
+	Vms           []VmDiskInventory
+	TotalSize     int
+}
+
+/*
+ Return the size of the disk in MB, 0 if unknown.
+*/
+func (d *Disk) SizeMB() int {
+	if d.Size == nil {
+		return 0
+	}
+	return *d.Size
+}
+
+/*
+ Summarize the disks of this VM, as loaded.
+*/
+func (vm *VirtualMachine) DiskInventory() VmDiskInventory {
+	inventory := VmDiskInventory{VmId: vm.Id, VmName: vm.Name, Disks: vm.Hardware.Disks}
+	if inventory.Disks == nil {
+		inventory.Disks = []Disk{}
+	}
+	for i := range inventory.Disks {
+		inventory.TotalSize += inventory.Disks[i].SizeMB()
+	}
+	return inventory
+}
+
+/*
+ Summarize the disks of all VMs in this environment, as loaded.
+*/
+func (e *Environment) DiskInventory() EnvironmentDiskInventory {
+	inventory := EnvironmentDiskInventory{EnvironmentId: e.Id, Vms: []VmDiskInventory{}}
+	for _, vm := range e.Vms {
+		vmInventory := vm.DiskInventory()
+		inventory.Vms = append(inventory.Vms, vmInventory)
+		inventory.TotalSize += vmInventory.TotalSize
+	}
+	return inventory
+}
@@ -29,14 +29,16 @@ const (
 Skytap Environment resource.
 */
 type Environment struct {
-	Id          string            `json:"id,omitempty"`
-	Url         string            `json:"url,omitempty"`
-	Name        string            `json:"name,omitempty"`
-	Description string            `json:"description,omitempty"`
-	Error       []string          `json:"errors,omitempty"`
-	Runstate    string            `json:"runstate,omitempty"`
-	Vms         []*VirtualMachine `json:"vms,omitempty"`
-	Networks    []Network         `json:"networks,omitempty"`
+	Id             string            `json:"id,omitempty"`
+	Url            string            `json:"url,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	Error          []string          `json:"errors,omitempty"`
+	Runstate       string            `json:"runstate,omitempty"`
+	Vms            []*VirtualMachine `json:"vms,omitempty"`
+	Networks       []Network         `json:"networks,omitempty"`
+	Tags           []Tag             `json:"tags,omitempty"`
+	SharingPortals []SharingPortal   `json:"publish_sets,omitempty"`
 }
 
 /*
@@ -220,6 +222,31 @@ func GetEnvironment(client SkytapClient, envId string) (*Environment, error) {
 	return env, err
 }
 
+/*
+ Restricts the environments returned by ListEnvironments. Empty fields don't filter.
+*/
+type EnvironmentFilter struct {
+	ProjectId string
+}
+
+/*
+ List the environments visible to the user, optionally limited by filter.
+*/
+func ListEnvironments(client SkytapClient, filter EnvironmentFilter) ([]Environment, error) {
+	path := EnvironmentPath + ".json"
+	if filter.ProjectId != "" {
+		path = projectChildrenPath(filter.ProjectId, EnvironmentPath)
+	}
+
+	listEnvs := func(s *sling.Sling) *sling.Sling {
+		return s.Get(path)
+	}
+
+	envs := []Environment{}
+	_, err := RunSkytapRequest(client, false, &envs, listEnvs)
+	return envs, err
+}
+
 /*
  Create a new environment from a template.
 */
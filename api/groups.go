@@ -0,0 +1,272 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	GroupPath      = "groups"
+	DepartmentPath = "departments"
+)
+
+/*
+ Skytap group resource, a set of users that can be granted access together.
+*/
+type Group struct {
+	Id          string `json:"id,omitempty"`
+	Url         string `json:"url,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Users       []User `json:"users,omitempty"`
+}
+
+/*
+ Skytap department resource. Every user belongs to exactly one department, which is used for quotas and reporting.
+*/
+type Department struct {
+	Id          string `json:"id,omitempty"`
+	Url         string `json:"url,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+/*
+ Request body for group and department update commands, nil fields are left unchanged.
+*/
+type GroupUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// Paths for groups and departments, which share the same layout.
+func adminIdPath(resourcePath string, id string) string {
+	return fmt.Sprintf("%s/%s.json", resourcePath, id)
+}
+func adminUsersPath(resourcePath string, id string) string {
+	return fmt.Sprintf("%s/%s/%s.json", resourcePath, id, UserPath)
+}
+func adminUserIdPath(resourcePath string, id string, userId string) string {
+	return fmt.Sprintf("%s/%s/%s/%s.json", resourcePath, id, UserPath, userId)
+}
+
+/*
+ List all groups of the company.
+*/
+func ListGroups(client SkytapClient) ([]Group, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(GroupPath + ".json")
+	}
+
+	groups := []Group{}
+	_, err := RunSkytapRequest(client, false, &groups, listReq)
+	return groups, err
+}
+
+/*
+ Return an existing group, including its users, by id.
+*/
+func GetGroup(client SkytapClient, groupId string) (*Group, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(adminIdPath(GroupPath, groupId))
+	}
+
+	group := &Group{}
+	_, err := RunSkytapRequest(client, false, group, getReq)
+	return group, err
+}
+
+/*
+ Create a new group.
+*/
+func CreateGroup(client SkytapClient, name string, description string) (*Group, error) {
+	log.WithFields(log.Fields{"name": name}).Info("Creating group")
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(GroupPath + ".json").BodyJSON(&Group{Name: name, Description: description})
+	}
+
+	group := &Group{}
+	_, err := RunSkytapRequest(client, false, group, createReq)
+	return group, err
+}
+
+/*
+ Update the name and/or description of a group. An empty description clears it.
+*/
+func UpdateGroup(client SkytapClient, groupId string, changes *GroupUpdate) (*Group, error) {
+	log.WithFields(log.Fields{"groupId": groupId}).Info("Updating group")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(adminIdPath(GroupPath, groupId)).BodyJSON(changes)
+	}
+
+	group := &Group{}
+	_, err := RunSkytapRequest(client, false, group, updateReq)
+	return group, err
+}
+
+/*
+ Delete a group by id. Its users are not deleted.
+*/
+func DeleteGroup(client SkytapClient, groupId string) error {
+	log.WithFields(log.Fields{"groupId": groupId}).Info("Deleting group")
+
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(adminIdPath(GroupPath, groupId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
+
+/*
+ List the users in a group.
+*/
+func (g *Group) ListUsers(client SkytapClient) ([]User, error) {
+	return listAdminUsers(client, GroupPath, g.Id)
+}
+
+/*
+ Add a user to a group.
+*/
+func (g *Group) AddUser(client SkytapClient, userId string) error {
+	log.WithFields(log.Fields{"groupId": g.Id, "userId": userId}).Info("Adding user to group")
+
+	return addAdminUser(client, GroupPath, g.Id, userId)
+}
+
+/*
+ Remove a user from a group.
+*/
+func (g *Group) RemoveUser(client SkytapClient, userId string) error {
+	log.WithFields(log.Fields{"groupId": g.Id, "userId": userId}).Info("Removing user from group")
+
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(adminUserIdPath(GroupPath, g.Id, userId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
+
+/*
+ List all departments of the company.
+*/
+func ListDepartments(client SkytapClient) ([]Department, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(DepartmentPath + ".json")
+	}
+
+	departments := []Department{}
+	_, err := RunSkytapRequest(client, false, &departments, listReq)
+	return departments, err
+}
+
+/*
+ Return an existing department by id.
+*/
+func GetDepartment(client SkytapClient, departmentId string) (*Department, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(adminIdPath(DepartmentPath, departmentId))
+	}
+
+	department := &Department{}
+	_, err := RunSkytapRequest(client, false, department, getReq)
+	return department, err
+}
+
+/*
+ Create a new department.
+*/
+func CreateDepartment(client SkytapClient, name string, description string) (*Department, error) {
+	log.WithFields(log.Fields{"name": name}).Info("Creating department")
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(DepartmentPath + ".json").BodyJSON(&Department{Name: name, Description: description})
+	}
+
+	department := &Department{}
+	_, err := RunSkytapRequest(client, false, department, createReq)
+	return department, err
+}
+
+/*
+ Update the name and/or description of a department. An empty description clears it.
+*/
+func UpdateDepartment(client SkytapClient, departmentId string, changes *GroupUpdate) (*Department, error) {
+	log.WithFields(log.Fields{"departmentId": departmentId}).Info("Updating department")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(adminIdPath(DepartmentPath, departmentId)).BodyJSON(changes)
+	}
+
+	department := &Department{}
+	_, err := RunSkytapRequest(client, false, department, updateReq)
+	return department, err
+}
+
+/*
+ Delete a department by id. The API rejects deleting departments that still have users.
+*/
+func DeleteDepartment(client SkytapClient, departmentId string) error {
+	log.WithFields(log.Fields{"departmentId": departmentId}).Info("Deleting department")
+
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(adminIdPath(DepartmentPath, departmentId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
+
+/*
+ List the users in a department.
+*/
+func (d *Department) ListUsers(client SkytapClient) ([]User, error) {
+	return listAdminUsers(client, DepartmentPath, d.Id)
+}
+
+/*
+ Move a user into a department. Users are removed from a department by adding them to another one.
+*/
+func (d *Department) AddUser(client SkytapClient, userId string) error {
+	log.WithFields(log.Fields{"departmentId": d.Id, "userId": userId}).Info("Adding user to department")
+
+	return addAdminUser(client, DepartmentPath, d.Id, userId)
+}
+
+func listAdminUsers(client SkytapClient, resourcePath string, id string) ([]User, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(adminUsersPath(resourcePath, id))
+	}
+
+	users := []User{}
+	_, err := RunSkytapRequest(client, false, &users, listReq)
+	return users, err
+}
+
+func addAdminUser(client SkytapClient, resourcePath string, id string, userId string) error {
+	addReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(adminUserIdPath(resourcePath, id, userId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, addReq)
+	return err
+}
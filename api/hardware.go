@@ -0,0 +1,349 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+ Set of hardware changes to apply to a VM at once, see VirtualMachine.ApplyHardwareChanges.
+
+ Sizes are in MB. Setters return the change set, so calls can be chained.
+*/
+type HardwareChangeSet struct {
+	cpus          *int
+	cpusPerSocket *int
+	ram           *int
+	newDisks      []int
+	resizedDisks  map[string]int
+	removedDisks  []string
+}
+
+func NewHardwareChangeSet() *HardwareChangeSet {
+	return &HardwareChangeSet{newDisks: []int{}, resizedDisks: map[string]int{}, removedDisks: []string{}}
+}
+
+func (c *HardwareChangeSet) SetCpus(cpus int) *HardwareChangeSet {
+	c.cpus = &cpus
+	return c
+}
+
+func (c *HardwareChangeSet) SetCpusPerSocket(cpusPerSocket int) *HardwareChangeSet {
+	c.cpusPerSocket = &cpusPerSocket
+	return c
+}
+
+func (c *HardwareChangeSet) SetRam(ram int) *HardwareChangeSet {
+	c.ram = &ram
+	return c
+}
+
+func (c *HardwareChangeSet) AddDisk(size int) *HardwareChangeSet {
+	c.newDisks = append(c.newDisks, size)
+	return c
+}
+
+func (c *HardwareChangeSet) ResizeDisk(diskId string, size int) *HardwareChangeSet {
+	c.resizedDisks[diskId] = size
+	return c
+}
+
+func (c *HardwareChangeSet) RemoveDisk(diskId string) *HardwareChangeSet {
+	c.removedDisks = append(c.removedDisks, diskId)
+	return c
+}
+
+/*
+ Returns true if the change set doesn't change anything.
+*/
+func (c *HardwareChangeSet) IsEmpty() bool {
+	return c.cpus == nil && c.cpusPerSocket == nil && c.ram == nil &&
+		len(c.newDisks) == 0 && len(c.resizedDisks) == 0 && len(c.removedDisks) == 0
+}
+
+/*
+ Check the changes against the limits the VM reports, and against its current disks.
+*/
+func (c *HardwareChangeSet) Validate(vm *VirtualMachine) error {
+	hw := vm.Hardware
+
+	cpus := intValue(hw.Cpus)
+	if c.cpus != nil {
+		cpus = *c.cpus
+		if cpus < 1 {
+			return fmt.Errorf("Invalid CPU count %d", cpus)
+		}
+		if hw.MaxCpus != nil && cpus > *hw.MaxCpus {
+			return fmt.Errorf("CPU count %d exceeds maximum of %d for VM %s", cpus, *hw.MaxCpus, vm.Id)
+		}
+	}
+	if c.cpusPerSocket != nil || c.cpus != nil {
+		cpusPerSocket := intValue(hw.CpusPerSocket)
+		if c.cpusPerSocket != nil {
+			cpusPerSocket = *c.cpusPerSocket
+		}
+		if cpusPerSocket < 1 || (cpus > 0 && cpus%cpusPerSocket != 0) {
+			return fmt.Errorf("CPU count %d is not a multiple of %d CPUs per socket", cpus, cpusPerSocket)
+		}
+	}
+	if c.ram != nil {
+		if hw.MinRam != nil && *c.ram < *hw.MinRam {
+			return fmt.Errorf("RAM %d is below minimum of %d for VM %s", *c.ram, *hw.MinRam, vm.Id)
+		}
+		if hw.MaxRam != nil && *c.ram > *hw.MaxRam {
+			return fmt.Errorf("RAM %d exceeds maximum of %d for VM %s", *c.ram, *hw.MaxRam, vm.Id)
+		}
+	}
+
+	for _, size := range c.newDisks {
+		if size <= 0 {
+			return fmt.Errorf("Invalid disk size %d", size)
+		}
+	}
+	for diskId, size := range c.resizedDisks {
+		disk := findDisk(hw.Disks, diskId)
+		if disk == nil {
+			return fmt.Errorf("VM %s has no disk %s", vm.Id, diskId)
+		}
+		if size < disk.SizeMB() {
+			return fmt.Errorf("Disk %s can't shrink from %d to %d", diskId, disk.SizeMB(), size)
+		}
+	}
+	for _, diskId := range c.removedDisks {
+		if findDisk(hw.Disks, diskId) == nil {
+			return fmt.Errorf("VM %s has no disk %s", vm.Id, diskId)
+		}
+		if _, ok := c.resizedDisks[diskId]; ok {
+			return fmt.Errorf("Disk %s is both resized and removed", diskId)
+		}
+	}
+	return nil
+}
+
+// Request body in the format of the VM update command.
+func (c *HardwareChangeSet) body() map[string]interface{} {
+	hw := map[string]interface{}{}
+	if c.cpus != nil {
+		hw["cpus"] = *c.cpus
+	}
+	if c.cpusPerSocket != nil {
+		hw["cpus_per_socket"] = *c.cpusPerSocket
+	}
+	if c.ram != nil {
+		hw["ram"] = *c.ram
+	}
+
+	disks := map[string]interface{}{}
+	if len(c.newDisks) > 0 {
+		disks["new"] = c.newDisks
+	}
+	existing := map[string]interface{}{}
+	for diskId, size := range c.resizedDisks {
+		existing[diskId] = map[string]interface{}{"id": diskId, "size": size}
+	}
+	// A size of zero deletes an existing disk
+	for _, diskId := range c.removedDisks {
+		existing[diskId] = map[string]interface{}{"id": diskId, "size": 0}
+	}
+	if len(existing) > 0 {
+		disks["existing"] = existing
+	}
+	if len(disks) > 0 {
+		hw["disks"] = disks
+	}
+	return map[string]interface{}{"hardware": hw}
+}
+
+func (c *HardwareChangeSet) diskIds() []string {
+	ids := append([]string{}, c.removedDisks...)
+	for diskId := range c.resizedDisks {
+		ids = append(ids, diskId)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+/*
+ Validate and apply all changes in a single update. A running VM is stopped once and started again afterwards; a
+ suspended VM can't be changed.
+*/
+func (vm *VirtualMachine) ApplyHardwareChanges(client SkytapClient, changes *HardwareChangeSet) (*VirtualMachine, error) {
+	if changes.IsEmpty() {
+		return vm, nil
+	}
+	if err := changes.Validate(vm); err != nil {
+		return vm, err
+	}
+
+	originalRunstate := vm.Runstate
+	if originalRunstate == RunStatePause {
+		return vm, errors.New("Unable to change the hardware of a suspended VM.")
+	}
+	if originalRunstate != RunStateStop {
+		stopped, err := vm.Stop(client)
+		if err != nil {
+			return stopped, err
+		}
+		vm = stopped
+	}
+
+	hardwareReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(vmUpdatePath(vm.Id)).BodyJSON(changes.body())
+	}
+
+	log.WithFields(log.Fields{"vmId": vm.Id, "disks": changes.diskIds(), "newDisks": changes.newDisks}).Info("Applying hardware changes")
+	newVm := &VirtualMachine{}
+	_, err := RunSkytapRequest(client, false, newVm, hardwareReq)
+	if err != nil {
+		// Leave the VM running as it was, the hardware update error is what the caller needs to see
+		if originalRunstate == RunStateStart {
+			started, startErr := vm.Start(client)
+			if startErr != nil {
+				log.WithFields(log.Fields{"vmId": vm.Id, "error": startErr}).Error("Unable to restart VM after failed hardware changes")
+				return vm, err
+			}
+			vm = started
+		}
+		return vm, err
+	}
+
+	if originalRunstate == RunStateStart {
+		return newVm.Start(client)
+	}
+	return newVm.WaitUntilReady(client)
+}
+
+func findDisk(disks []Disk, diskId string) *Disk {
+	for i := range disks {
+		if disks[i].Id == diskId {
+			return &disks[i]
+		}
+	}
+	return nil
+}
+
+// Hardware settings can only be changed on stopped VMs. Unlike UpdateHardware, settings setters don't stop the VM.
+func (vm *VirtualMachine) requireStopped() error {
+	if vm.Runstate != RunStateStop {
+		return fmt.Errorf("VM %s must be stopped to change hardware settings, it is %s", vm.Id, vm.Runstate)
+	}
+	return nil
+}
+
+func intValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+/*
+ Enable or disable nested virtualization, i.e. running a hypervisor inside the VM. The VM must be stopped.
+*/
+func (vm *VirtualMachine) SetNestedVirtualization(client SkytapClient, enabled bool) (*VirtualMachine, error) {
+	return vm.updateHardwareSettings(client, Hardware{NestedVirtualization: &enabled})
+}
+
+/*
+ Enable or disable synchronization of the guest clock with the host. The VM must be stopped.
+*/
+func (vm *VirtualMachine) SetTimeSync(client SkytapClient, enabled bool) (*VirtualMachine, error) {
+	return vm.updateHardwareSettings(client, Hardware{TimeSyncEnabled: &enabled})
+}
+
+/*
+ Enable or disable copy and paste between the browser client and the VM. The VM must be stopped.
+*/
+func (vm *VirtualMachine) SetCopyPaste(client SkytapClient, enabled bool) (*VirtualMachine, error) {
+	return vm.updateHardwareSettings(client, Hardware{CopyPasteEnabled: &enabled})
+}
+
+/*
+ Set the keyboard layout used for VNC connections, e.g. "en-us" or "de". The VM must be stopped.
+*/
+func (vm *VirtualMachine) SetVncKeymap(client SkytapClient, keymap string) (*VirtualMachine, error) {
+	return vm.updateHardwareSettings(client, Hardware{VncKeymap: &keymap})
+}
+
+/*
+ Set the time the VM clock starts at when the VM is powered on. Time sync should be disabled for this to stick. The
+ VM must be stopped.
+*/
+func (vm *VirtualMachine) SetRtcStartTime(client SkytapClient, start time.Time) (*VirtualMachine, error) {
+	rtcStartTime := start.UTC().Format(RtcTimeFormat)
+	return vm.updateHardwareSettings(client, Hardware{RtcStartTime: &rtcStartTime})
+}
+
+func (vm *VirtualMachine) updateHardwareSettings(client SkytapClient, hardware Hardware) (*VirtualMachine, error) {
+	if err := vm.requireStopped(); err != nil {
+		return vm, err
+	}
+
+	hardwareReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(vmUpdatePath(vm.Id)).BodyJSON(hardware.updateBody())
+	}
+
+	newVm := &VirtualMachine{}
+
+	log.WithFields(log.Fields{"vmId": vm.Id}).Infof("Updating VM hardware settings: %+v", hardware)
+	_, err := RunSkytapRequest(client, false, newVm, hardwareReq)
+	return newVm, err
+}
+
+/*
+ Returns true if the VM hardware version is below the latest version Skytap supports for it.
+*/
+func (vm *VirtualMachine) CanUpgradeHardwareVersion() bool {
+	if vm.Hardware.Upgradable != nil && !*vm.Hardware.Upgradable {
+		return false
+	}
+	return vm.HardwareVersion > 0 && vm.HardwareVersion < vm.MaxHardwareVersion
+}
+
+/*
+ Upgrade the VM hardware version to max_hardware_version. The VM must be stopped, and the upgrade can't be undone.
+*/
+func (vm *VirtualMachine) UpgradeHardwareVersion(client SkytapClient) (*VirtualMachine, error) {
+	if err := vm.requireStopped(); err != nil {
+		return vm, err
+	}
+	if !vm.CanUpgradeHardwareVersion() {
+		return vm, fmt.Errorf("VM %s can't be upgraded from hardware version %d (maximum %d)", vm.Id, vm.HardwareVersion, vm.MaxHardwareVersion)
+	}
+
+	upgradeReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(vmUpdatePath(vm.Id)).BodyJSON(&HardwareVersionUpdate{HardwareVersion: vm.MaxHardwareVersion})
+	}
+
+	newVm := &VirtualMachine{}
+
+	log.WithFields(log.Fields{"vmId": vm.Id, "from": vm.HardwareVersion, "to": vm.MaxHardwareVersion}).Info("Upgrading VM hardware version")
+	_, err := RunSkytapRequest(client, false, newVm, upgradeReq)
+	return newVm, err
+}
+
+/*
+ Request body for hardware version upgrade commands.
+*/
+type HardwareVersionUpdate struct {
+	HardwareVersion int `json:"hardware_version"`
+}
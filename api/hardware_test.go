@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHardwareChangeSetValidate(t *testing.T) {
+	vmJson := readJson(t, "testdata/vm-1001.json")
+	vm := &VirtualMachine{}
+	require.NoError(t, json.Unmarshal([]byte(vmJson), vm))
+
+	require.NoError(t, NewHardwareChangeSet().SetCpus(4).SetCpusPerSocket(2).SetRam(4096).Validate(vm))
+	require.Error(t, NewHardwareChangeSet().SetCpus(16).Validate(vm), "Should exceed max CPUs")
+	require.Error(t, NewHardwareChangeSet().SetCpus(3).SetCpusPerSocket(2).Validate(vm), "Should require CPU multiple of sockets")
+	require.Error(t, NewHardwareChangeSet().SetRam(128).Validate(vm), "Should be below min RAM")
+	require.Error(t, NewHardwareChangeSet().ResizeDisk("disk-5971736-13548234-scsi-0-0", 1024).Validate(vm), "Should not shrink disk")
+	require.Error(t, NewHardwareChangeSet().RemoveDisk("disk-missing").Validate(vm), "Should require existing disk")
+}
+
+func TestApplyHardwareChanges(t *testing.T) {
+	vmJson := readJson(t, "testdata/vm-1001.json")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	runstate := RunStateStart
+	hardwareUpdates := 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/vms/1001":
+		case r.Method == "PUT" && r.URL.Path == "/vms/1001":
+			body := &RunstateBody{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(body))
+			runstate = body.Runstate
+		case r.Method == "PUT" && r.URL.Path == "/vms/1001.json":
+			require.Equal(t, RunStateStop, runstate, "Should stop before changing hardware")
+			hardwareUpdates++
+			body, _ := ioutil.ReadAll(r.Body)
+			require.Equal(t, `{"hardware":{"cpus":2,"disks":{"existing":{"disk-5971736-13548234-scsi-0-0":{"id":"disk-5971736-13548234-scsi-0-0","size":40960}},`+
+				`"new":[10240]},"ram":2048}}`, strings.TrimSpace(string(body)))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprintln(w, strings.Replace(vmJson, `"runstate": "stopped"`, fmt.Sprintf(`"runstate": "%s"`, runstate), 1))
+	})
+
+	vm, err := GetVirtualMachine(client, "1001")
+	require.NoError(t, err, "Error getting VM")
+	require.Equal(t, RunStateStart, vm.Runstate)
+
+	changes := NewHardwareChangeSet().SetCpus(2).SetRam(2048).AddDisk(10240).ResizeDisk("disk-5971736-13548234-scsi-0-0", 40960)
+	vm, err = vm.ApplyHardwareChanges(client, changes)
+	require.NoError(t, err, "Error applying hardware changes")
+	require.Equal(t, 1, hardwareUpdates)
+	require.Equal(t, RunStateStart, vm.Runstate, "Should restore runstate")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/vms/1001":
+		case r.Method == "PUT" && r.URL.Path == "/vms/1001":
+			body := &RunstateBody{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(body))
+			runstate = body.Runstate
+		case r.Method == "PUT" && r.URL.Path == "/vms/1001.json":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprintln(w, `{"error":"Invalid hardware"}`)
+			return
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprintln(w, strings.Replace(vmJson, `"runstate": "stopped"`, fmt.Sprintf(`"runstate": "%s"`, runstate), 1))
+	})
+
+	vm, err = vm.ApplyHardwareChanges(client, NewHardwareChangeSet().SetCpus(4))
+	require.Error(t, err, "Should fail when the hardware update fails")
+	require.Equal(t, RunStateStart, runstate, "Should restart the VM after a failed update")
+	require.Equal(t, RunStateStart, vm.Runstate)
+}
+
+func TestHardwareSettings(t *testing.T) {
+	vmJson := readJson(t, "testdata/vm-1001.json")
+
+	client := skytapClient(t)
+	server := getMockServerForString(client, vmJson)
+	defer server.Close()
+
+	vm, err := GetVirtualMachine(client, "1001")
+	require.NoError(t, err, "Error getting VM")
+	require.Equal(t, "centos-64", vm.Hardware.GuestOs)
+	require.Equal(t, 12, *vm.Hardware.MaxCpus)
+	require.Equal(t, 20480, *vm.Hardware.Storage)
+	require.True(t, *vm.Hardware.TimeSyncEnabled)
+	require.False(t, *vm.Hardware.NestedVirtualization)
+	require.Nil(t, vm.Hardware.RtcStartTime)
+	require.True(t, vm.CanUpgradeHardwareVersion())
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/vms/1001.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"hardware":{"nested_virtualization":true}}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, strings.Replace(vmJson, `"nested_virtualization": false`, `"nested_virtualization": true`, 1))
+	})
+	updated, err := vm.SetNestedVirtualization(client, true)
+	require.NoError(t, err, "Error enabling nested virtualization")
+	require.True(t, *updated.Hardware.NestedVirtualization)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"hardware":{"rtc_start_time":"2030/01/02 03:04:05"}}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, vmJson)
+	})
+	_, err = vm.SetRtcStartTime(client, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err, "Error setting RTC start time")
+
+	running := *vm
+	running.Runstate = RunStateStart
+	_, err = running.SetTimeSync(client, false)
+	require.Error(t, err, "Should require a stopped VM")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"hardware_version":11}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, strings.Replace(vmJson, `"hardware_version": 10`, `"hardware_version": 11`, 1))
+	})
+	upgraded, err := vm.UpgradeHardwareVersion(client)
+	require.NoError(t, err, "Error upgrading hardware version")
+	require.False(t, upgraded.CanUpgradeHardwareVersion())
+}
@@ -0,0 +1,332 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// Prefix lengths Skytap accepts for network subnets.
+	MinSubnetPrefix = 16
+	MaxSubnetPrefix = 29
+
+	SubnetConflictNetwork = "network"
+	SubnetConflictTunnel  = "tunnel"
+	SubnetConflictVpn     = "vpn"
+)
+
+/*
+ Existing subnet that overlaps a planned one, see Environment.SubnetConflicts.
+*/
+type SubnetConflict struct {
+	Subnet string
+	// One of the SubnetConflict* constants
+	Source string
+	// Name of the network or VPN the subnet belongs to
+	Name string
+}
+
+func (c SubnetConflict) String() string {
+	return fmt.Sprintf("%s %s (%s)", c.Source, c.Name, c.Subnet)
+}
+
+/*
+ Parse and check a network subnet in CIDR notation. The address must be the network address, e.g. 10.0.0.0/24 rather
+ than 10.0.0.1/24, and the prefix length must be within MinSubnetPrefix and MaxSubnetPrefix.
+*/
+func ValidateSubnet(subnet string) (*net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid subnet '%s': %s", subnet, err)
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("Invalid subnet '%s': only IPv4 is supported", subnet)
+	}
+	if !ip.Equal(ipNet.IP) {
+		return nil, fmt.Errorf("Invalid subnet '%s': address is not the network address %s", subnet, ipNet.IP)
+	}
+	prefix, _ := ipNet.Mask.Size()
+	if prefix < MinSubnetPrefix || prefix > MaxSubnetPrefix {
+		return nil, fmt.Errorf("Invalid subnet '%s': prefix length must be between %d and %d", subnet, MinSubnetPrefix, MaxSubnetPrefix)
+	}
+	return ipNet, nil
+}
+
+/*
+ Check that the gateway is a usable host address of the subnet, i.e. neither its network nor its broadcast address.
+*/
+func ValidateGateway(subnet string, gateway string) error {
+	ipNet, err := ValidateSubnet(subnet)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(gateway).To4()
+	if ip == nil {
+		return fmt.Errorf("Invalid gateway '%s'", gateway)
+	}
+	if !ipNet.Contains(ip) {
+		return fmt.Errorf("Gateway %s is outside of subnet %s", gateway, subnet)
+	}
+	first, last := subnetRange(ipNet)
+	if addr := ipToUint32(ip); addr == first || addr == last {
+		return fmt.Errorf("Gateway %s is the network or broadcast address of subnet %s", gateway, subnet)
+	}
+	return nil
+}
+
+/*
+ Return the subnets routable from this environment that overlap the given one: its networks, the networks they are
+ tunneled to, and the remote subnets of attached VPNs. Uses the environment as loaded.
+*/
+func (e *Environment) SubnetConflicts(subnet string) ([]SubnetConflict, error) {
+	if _, err := ValidateSubnet(subnet); err != nil {
+		return nil, err
+	}
+
+	conflicts := []SubnetConflict{}
+	for _, used := range e.usedSubnets() {
+		overlap, err := subnetsOverlap(subnet, used.Subnet)
+		if err != nil {
+			// Ignore malformed subnets reported by the API, they can't be planned around
+			continue
+		}
+		if overlap {
+			conflicts = append(conflicts, used)
+		}
+	}
+	return conflicts, nil
+}
+
+/*
+ Check a subnet and optional gateway for a new network in this environment. Returns an error listing all conflicts.
+*/
+func (e *Environment) ValidateNewNetwork(subnet string, gateway string) error {
+	if gateway != "" {
+		if err := ValidateGateway(subnet, gateway); err != nil {
+			return err
+		}
+	}
+	conflicts, err := e.SubnetConflicts(subnet)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		descriptions := []string{}
+		for _, conflict := range conflicts {
+			descriptions = append(descriptions, conflict.String())
+		}
+		return fmt.Errorf("Subnet %s overlaps %s", subnet, strings.Join(descriptions, ", "))
+	}
+	return nil
+}
+
+/*
+ Suggest the first subnet with the given prefix length inside the range that has no conflicts in this environment, e.g.
+ NextFreeSubnet("10.0.0.0/16", 24).
+*/
+func (e *Environment) NextFreeSubnet(within string, prefix int) (string, error) {
+	_, rangeNet, err := net.ParseCIDR(within)
+	if err != nil || rangeNet.IP.To4() == nil {
+		return "", fmt.Errorf("Invalid range '%s'", within)
+	}
+	rangePrefix, _ := rangeNet.Mask.Size()
+	if prefix < rangePrefix || prefix < MinSubnetPrefix || prefix > MaxSubnetPrefix {
+		return "", fmt.Errorf("Prefix length %d doesn't fit range %s", prefix, within)
+	}
+
+	first, last := subnetRange(rangeNet)
+	size := uint32(1) << uint(32-prefix)
+	for start := first; start <= last && start >= first; start += size {
+		candidate := fmt.Sprintf("%s/%d", uint32ToIp(start), prefix)
+		conflicts, err := e.SubnetConflicts(candidate)
+		if err != nil {
+			return "", err
+		}
+		if len(conflicts) == 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("No free /%d subnet left in %s", prefix, within)
+}
+
+func (e *Environment) usedSubnets() []SubnetConflict {
+	used := []SubnetConflict{}
+	for _, network := range e.Networks {
+		if network.Subnet != "" {
+			used = append(used, SubnetConflict{Subnet: network.Subnet, Source: SubnetConflictNetwork, Name: network.Name})
+		}
+		for _, tunnel := range network.Tunnels {
+			peer := tunnel.TargetNetwork
+			if peer.Id == network.Id {
+				peer = tunnel.SourceNetwork
+			}
+			if peer.Subnet != "" {
+				used = append(used, SubnetConflict{Subnet: peer.Subnet, Source: SubnetConflictTunnel, Name: peer.Name})
+			}
+		}
+		for _, attachment := range network.VpnAttachments {
+			for _, cidr := range attachment.Vpn.RemoteSubnets.Cidrs() {
+				used = append(used, SubnetConflict{Subnet: cidr, Source: SubnetConflictVpn, Name: attachment.Vpn.Name})
+			}
+		}
+	}
+	return used
+}
+
+/*
+ Returns true if the two subnets, in CIDR notation, share any address.
+*/
+func subnetsOverlap(a string, b string) (bool, error) {
+	_, netA, err := net.ParseCIDR(a)
+	if err != nil {
+		return false, fmt.Errorf("Invalid subnet '%s': %s", a, err)
+	}
+	_, netB, err := net.ParseCIDR(b)
+	if err != nil {
+		return false, fmt.Errorf("Invalid subnet '%s': %s", b, err)
+	}
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP), nil
+}
+
+// First and last (network and broadcast) address of an IPv4 subnet.
+func subnetRange(ipNet *net.IPNet) (uint32, uint32) {
+	first := ipToUint32(ipNet.IP)
+	prefix, bits := ipNet.Mask.Size()
+	return first, first | (uint32(1)<<uint(bits-prefix) - 1)
+}
+
+func ipToUint32(ip net.IP) uint32 { return binary.BigEndian.Uint32(ip.To4()) }
+func uint32ToIp(addr uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, addr)
+	return ip
+}
+
+/*
+ Hands out free static IPs of a network. The network and broadcast addresses, the gateway and the IPs of all interfaces
+ in the environment that are on the network are never handed out. Ranges served by a DHCP server, or otherwise kept free,
+ can be excluded with Reserve.
+*/
+type IpAllocator struct {
+	networkId string
+	ipNet     *net.IPNet
+	used      map[uint32]bool
+}
+
+/*
+ Create an allocator for a network of the environment, using the environment as loaded.
+*/
+func NewIpAllocator(env *Environment, network *Network) (*IpAllocator, error) {
+	ipNet, err := ValidateSubnet(network.Subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	allocator := &IpAllocator{networkId: network.Id, ipNet: ipNet, used: map[uint32]bool{}}
+	first, last := subnetRange(ipNet)
+	allocator.used[first] = true
+	allocator.used[last] = true
+	allocator.Use(network.Gateway)
+
+	for _, vm := range env.Vms {
+		for _, nic := range vm.Interfaces {
+			if nic.NetworkId == "" || nic.NetworkId == network.Id {
+				allocator.Use(nic.Ip)
+			}
+		}
+	}
+	return allocator, nil
+}
+
+/*
+ Mark an address as taken. Addresses outside the network are ignored.
+*/
+func (a *IpAllocator) Use(ip string) {
+	addr := net.ParseIP(ip).To4()
+	if addr != nil && a.ipNet.Contains(addr) {
+		a.used[ipToUint32(addr)] = true
+	}
+}
+
+/*
+ Id of the network addresses are handed out for.
+*/
+func (a *IpAllocator) NetworkId() string {
+	return a.networkId
+}
+
+/*
+ Mark an address handed out by Next as free again, e.g. when it couldn't be assigned.
+*/
+func (a *IpAllocator) Release(ip string) {
+	addr := net.ParseIP(ip).To4()
+	if addr != nil && a.ipNet.Contains(addr) {
+		delete(a.used, ipToUint32(addr))
+	}
+}
+
+/*
+ Exclude an inclusive range of addresses, e.g. a DHCP pool. Both ends must be inside the network and start must not
+ be after end.
+*/
+func (a *IpAllocator) Reserve(start string, end string) error {
+	startIp := net.ParseIP(start).To4()
+	endIp := net.ParseIP(end).To4()
+	if startIp == nil || endIp == nil {
+		return fmt.Errorf("Invalid range %s - %s", start, end)
+	}
+	if !a.ipNet.Contains(startIp) || !a.ipNet.Contains(endIp) {
+		return fmt.Errorf("Range %s - %s is not inside %s", start, end, a.ipNet)
+	}
+	if ipToUint32(startIp) > ipToUint32(endIp) {
+		return fmt.Errorf("Invalid range %s - %s, start is after end", start, end)
+	}
+	for addr := uint64(ipToUint32(startIp)); addr <= uint64(ipToUint32(endIp)); addr++ {
+		a.used[uint32(addr)] = true
+	}
+	return nil
+}
+
+/*
+ Return all free addresses, lowest first.
+*/
+func (a *IpAllocator) Free() []string {
+	free := []string{}
+	first, last := subnetRange(a.ipNet)
+	for addr := first; addr <= last; addr++ {
+		if !a.used[addr] {
+			free = append(free, uint32ToIp(addr).String())
+		}
+	}
+	return free
+}
+
+/*
+ Return the lowest free address and mark it as taken, so repeated calls return distinct addresses.
+*/
+func (a *IpAllocator) Next() (string, error) {
+	first, last := subnetRange(a.ipNet)
+	for addr := first; addr <= last; addr++ {
+		if !a.used[addr] {
+			a.used[addr] = true
+			return uint32ToIp(addr).String(), nil
+		}
+	}
+	return "", fmt.Errorf("No free IP left in %s", a.ipNet)
+}
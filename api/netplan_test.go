@@ -0,0 +1,84 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSubnet(t *testing.T) {
+	_, err := ValidateSubnet("10.0.0.0/24")
+	require.NoError(t, err)
+	_, err = ValidateSubnet("10.0.0.1/24")
+	require.Error(t, err, "Should reject host address")
+	_, err = ValidateSubnet("10.0.0.0/30")
+	require.Error(t, err, "Should reject too small subnet")
+	_, err = ValidateSubnet("10.0.0/24")
+	require.Error(t, err, "Should reject malformed subnet")
+
+	require.NoError(t, ValidateGateway("10.0.1.0/24", "10.0.1.254"))
+	require.Error(t, ValidateGateway("10.0.1.0/24", "10.0.2.1"), "Should reject gateway outside subnet")
+	require.Error(t, ValidateGateway("10.0.1.0/24", "10.0.1.255"), "Should reject broadcast gateway")
+}
+
+func TestSubnetConflicts(t *testing.T) {
+	env := &Environment{Networks: []Network{{
+		Id:     "99",
+		Name:   "Default Network",
+		Subnet: "10.0.0.0/24",
+		Tunnels: []Tunnel{{
+			SourceNetwork: Network{Id: "99", Subnet: "10.0.0.0/24"},
+			TargetNetwork: Network{Id: "98", Name: "Peer", Subnet: "10.0.2.0/24"},
+		}},
+		VpnAttachments: []VpnAttachment{{Vpn: Vpn{Name: "VPN 1", RemoteSubnets: VpnSubnets{{CidrBlock: "10.0.4.0/23"}}}}},
+	}}}
+
+	conflicts, err := env.SubnetConflicts("10.0.0.0/22")
+	require.NoError(t, err)
+	require.Len(t, conflicts, 2)
+	require.Equal(t, SubnetConflictNetwork, conflicts[0].Source)
+	require.Equal(t, SubnetConflictTunnel, conflicts[1].Source)
+
+	require.Error(t, env.ValidateNewNetwork("10.0.5.0/24", ""), "Should conflict with VPN remote subnet")
+	require.NoError(t, env.ValidateNewNetwork("10.0.1.0/24", "10.0.1.1"))
+
+	next, err := env.NextFreeSubnet("10.0.0.0/16", 24)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.1.0/24", next)
+	next, err = env.NextFreeSubnet("10.0.0.0/16", 23)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.6.0/23", next)
+	_, err = env.NextFreeSubnet("10.0.0.0/23", 23)
+	require.Error(t, err, "Should have no free subnet")
+}
+
+func TestIpAllocator(t *testing.T) {
+	env := &Environment{Vms: []*VirtualMachine{{Interfaces: []*NetworkInterface{
+		{Ip: "10.0.1.1", NetworkId: "99"},
+		{Ip: "10.0.1.2", NetworkId: "98"},
+	}}}}
+	network := &Network{Id: "99", Subnet: "10.0.1.0/29", Gateway: "10.0.1.6"}
+
+	allocator, err := NewIpAllocator(env, network)
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.1.2", "10.0.1.3", "10.0.1.4", "10.0.1.5"}, allocator.Free())
+
+	require.Error(t, allocator.Reserve("10.0.1.3", "10.0.1.2"), "Should reject reversed range")
+	require.Error(t, allocator.Reserve("10.0.1.2", "10.0.2.3"), "Should reject range outside subnet")
+	require.Equal(t, 4, len(allocator.Free()))
+	require.NoError(t, allocator.Reserve("10.0.1.2", "10.0.1.3"))
+	ip, err := allocator.Next()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.1.4", ip)
+	ip, err = allocator.Next()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.1.5", ip)
+	_, err = allocator.Next()
+	require.Error(t, err, "Should have no free IP left")
+
+	allocator.Release("10.0.1.4")
+	ip, err = allocator.Next()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.1.4", ip)
+	require.Equal(t, "99", allocator.NetworkId())
+}
@@ -15,7 +15,10 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 
 	"github.com/dghubble/sling"
 
@@ -41,7 +44,7 @@ type Network struct {
 	Gateway             string          `json:"gateway"`
 	NetworkType         string          `json:"network_type"`
 	Tunnelable          bool            `json:"tunnelable"`
-	Tunnels             interface{}     `json:"tunnels"`
+	Tunnels             []Tunnel        `json:"tunnels"`
 	PrimaryNameserver   string          `json:"primary_nameserver"`
 	SecondaryNameserver string          `json:"secondary_nameserver"`
 	Region              string          `json:"region"`
@@ -113,19 +116,21 @@ type PublicIp struct {
  VPN attachments to network.
 */
 type VpnAttachment struct {
-	Id        string `json:"id"`
-	Connected bool   `json:"connected"`
-	Vpn       Vpn    `json:"vpn"`
+	Id        string     `json:"id"`
+	Connected bool       `json:"connected"`
+	Network   VpnNetwork `json:"network"`
+	Vpn       Vpn        `json:"vpn"`
 }
 
-type Vpn struct {
-	Id            string `json:"id"`
-	Name          string `json:"name"`
-	Enabled       bool   `json:"enabled"`
-	NatEnabled    bool   `json:"nat_enabled"`
-	RemoteSubnets string `json:"remote_subnets"`
-	RemotePeerIp  string `json:"remote_subnets"`
-	CanReconnect  bool   `json:"can_reconnect"`
+/*
+ Network side of a VPN attachment.
+*/
+type VpnNetwork struct {
+	Id                string `json:"id"`
+	Subnet            string `json:"subnet"`
+	NetworkName       string `json:"network_name"`
+	ConfigurationId   string `json:"configuration_id"`
+	ConfigurationName string `json:"configuration_name,omitempty"`
 }
 
 /*
@@ -169,6 +174,10 @@ func CreateAutomaticNetwork(
 
 	log.WithFields(log.Fields{"envId": envId, "network_name": name}).Info("Adding network to environment")
 
+	if _, err := ValidateSubnet(subnet); err != nil {
+		return nil, err
+	}
+
 	createAutoNetwork := func(s *sling.Sling) *sling.Sling {
 		network := struct {
 			Name        string `json:"name"`
@@ -199,6 +208,10 @@ func CreateManualNetwork(
 	gateway string) (*Network, error) {
 	log.WithFields(log.Fields{"envId": envId, "network_name": name}).Info("Adding network to environment")
 
+	if err := ValidateGateway(subnet, gateway); err != nil {
+		return nil, err
+	}
+
 	createAutoNetwork := func(s *sling.Sling) *sling.Sling {
 		network := struct {
 			Name        string `json:"name"`
@@ -232,6 +245,104 @@ func DeleteNetwork(client SkytapClient, envId string, netId string) error {
 	return err
 }
 
+func networksPath(envId string) string {
+	return fmt.Sprintf("%s/%s/%s.json", EnvironmentPath, envId, NetworkPath)
+}
+func networkIdPath(envId string, netId string) string {
+	return fmt.Sprintf("%s/%s/%s/%s.json", EnvironmentPath, envId, NetworkPath, netId)
+}
+
+/*
+ Request body for network update commands. Only non-empty fields are changed.
+
+ Nameservers holds the primary and secondary nameserver: nil leaves them unchanged, an empty slice clears both.
+*/
+type NetworkUpdate struct {
+	Name        string    `json:"name,omitempty"`
+	Subnet      string    `json:"subnet,omitempty"`
+	Domain      string    `json:"domain,omitempty"`
+	Gateway     string    `json:"gateway,omitempty"`
+	Nameservers *[]string `json:"-"`
+	NatSubnet   string    `json:"nat_subnet,omitempty"`
+}
+
+func (u NetworkUpdate) MarshalJSON() ([]byte, error) {
+	type fields NetworkUpdate
+	body := struct {
+		fields
+		PrimaryNameserver   *string `json:"primary_nameserver,omitempty"`
+		SecondaryNameserver *string `json:"secondary_nameserver,omitempty"`
+	}{fields: fields(u)}
+	if u.Nameservers != nil {
+		nameservers := *u.Nameservers
+		if len(nameservers) > 2 {
+			return nil, fmt.Errorf("A network has at most 2 nameservers, got %d", len(nameservers))
+		}
+		primary, secondary := "", ""
+		if len(nameservers) > 0 {
+			primary = nameservers[0]
+		}
+		if len(nameservers) > 1 {
+			secondary = nameservers[1]
+		}
+		body.PrimaryNameserver, body.SecondaryNameserver = &primary, &secondary
+	}
+	return json.Marshal(body)
+}
+
+/*
+ List the networks of an environment.
+*/
+func ListNetworks(client SkytapClient, envId string) ([]Network, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(networksPath(envId))
+	}
+
+	networks := []Network{}
+	_, err := RunSkytapRequest(client, false, &networks, listReq)
+	return networks, err
+}
+
+/*
+ Return a network of an environment by id.
+*/
+func GetNetwork(client SkytapClient, envId string, netId string) (*Network, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(networkIdPath(envId, netId))
+	}
+
+	network := &Network{}
+	_, err := RunSkytapRequest(client, false, network, getReq)
+	return network, err
+}
+
+/*
+ Change settings of a network. Changing the subnet or gateway may require the environment to be stopped.
+*/
+func UpdateNetwork(client SkytapClient, envId string, netId string, update *NetworkUpdate) (*Network, error) {
+	log.WithFields(log.Fields{"envId": envId, "netId": netId, "update": update}).Info("Updating network in environment")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(networkIdPath(envId, netId)).BodyJSON(update)
+	}
+
+	network := &Network{}
+	_, err := RunSkytapRequest(client, false, network, updateReq)
+	return network, err
+}
+
+/*
+ Return the network with the given name from the environment as loaded, or nil if not present.
+*/
+func (e *Environment) FindNetworkByName(name string) *Network {
+	for i := range e.Networks {
+		if e.Networks[i].Name == name {
+			return &e.Networks[i]
+		}
+	}
+	return nil
+}
+
 /*
  Path for all VPNs in a network and environment.
 */
@@ -316,31 +427,104 @@ func (n *Network) DetachFromVpn(client SkytapClient, envId string, vpnId string)
 	return err
 }
 
-func vpnIdPath(vpnId string) string { return VpnPath + "/" + vpnId + ".json" }
+func publishedServicesPath(envId string, vmId string, interfaceId string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s.json", EnvironmentPath, envId, VmPath, vmId, InterfacePath, interfaceId, PublishedServicePath)
+}
+func publishedServicePath(envId string, vmId string, interfaceId string, serviceId string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s/%s.json", EnvironmentPath, envId, VmPath, vmId, InterfacePath, interfaceId, PublishedServicePath, serviceId)
+}
+
+// Pseudo runstates of a VPN attachment, used when waiting for a connection.
+const (
+	vpnStateConnected    = "connected"
+	vpnStateDisconnected = "disconnected"
+)
+
+/*
+ Return the attachment of this network to the given VPN, as loaded, or nil if not attached.
+*/
+func (n *Network) FindVpnAttachment(vpnId string) *VpnAttachment {
+	for i := range n.VpnAttachments {
+		if n.VpnAttachments[i].Vpn.Id == vpnId {
+			return &n.VpnAttachments[i]
+		}
+	}
+	return nil
+}
+
+/*
+ Make sure the network is attached and connected to a VPN, in the context of the given environment.
+
+ Attaches only if not attached yet and connects only if disconnected, so it is safe to call repeatedly. Waits until the
+ connection is reported up, and returns the final attachment.
+*/
+func (n *Network) EnsureVpnConnected(client SkytapClient, envId string, vpnId string) (*VpnAttachment, error) {
+	log.WithFields(log.Fields{"netId": n.Id, "vpnId": vpnId, "envId": envId}).Info("Ensuring network is connected to VPN")
+
+	current, err := GetNetwork(client, envId, n.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := current.FindVpnAttachment(vpnId)
+	if attachment == nil {
+		if _, err = n.AttachToVpn(client, envId, vpnId); err != nil {
+			return nil, err
+		}
+	}
+	if attachment == nil || !attachment.Connected {
+		if err = n.ConnectToVpn(client, envId, vpnId); err != nil {
+			return nil, err
+		}
+	}
+
+	connection := &vpnConnection{envId: envId, netId: n.Id, vpnId: vpnId, attachment: attachment}
+	result, err := WaitUntilInState(client, []string{vpnStateConnected}, connection, false)
+	connection = result.(*vpnConnection)
+	if connection.network != nil {
+		n.VpnAttachments = connection.network.VpnAttachments
+	}
+	return connection.attachment, err
+}
 
 /*
- Return an existing VPN by id.
+ Adapts a VPN attachment to RunstateAwareResource, so WaitUntilInState can poll it.
 */
-func GetVpn(client SkytapClient, vpnId string) (*Vpn, error) {
-	vpn := &Vpn{}
+type vpnConnection struct {
+	envId      string
+	netId      string
+	vpnId      string
+	network    *Network
+	attachment *VpnAttachment
+}
 
-	getVpn := func(s *sling.Sling) *sling.Sling {
-		return s.Get(vpnIdPath(vpnId))
+func (c *vpnConnection) RunstateStr() string {
+	if c.attachment != nil && c.attachment.Connected {
+		return vpnStateConnected
 	}
+	return vpnStateDisconnected
+}
 
-	_, err := RunSkytapRequest(client, true, vpn, getVpn)
-	return vpn, err
+func (c *vpnConnection) Refresh(client SkytapClient) (RunstateAwareResource, error) {
+	network, err := GetNetwork(client, c.envId, c.netId)
+	if err != nil {
+		return c, err
+	}
+	return &vpnConnection{envId: c.envId, netId: c.netId, vpnId: c.vpnId, network: network, attachment: network.FindVpnAttachment(c.vpnId)}, nil
 }
 
+/*
+ Publish an internal port of this interface. The added service, including the assigned external endpoint, is
+ appended to PublishedServices.
+*/
 func (nic *NetworkInterface) AddPublishedService(client SkytapClient, port int, envId, vmId string) (*NetworkInterface, error) {
 
 	log.WithFields(log.Fields{"envId": envId, "vmId": vmId, "interfaceId": nic.Id}).Infof("Adding service")
 
-	service := PublishedService{InternalPort: port}
+	service := &PublishedService{InternalPort: port}
 
 	addReq := func(s *sling.Sling) *sling.Sling {
-		path := fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s.json", EnvironmentPath, envId, VmPath, vmId, InterfacePath, nic.Id, PublishedServicePath)
-		return s.Post(path).BodyJSON(service)
+		return s.Post(publishedServicesPath(envId, vmId, nic.Id)).BodyJSON(service)
 	}
 
 	_, err := RunSkytapRequest(client, true, service, addReq)
@@ -348,9 +532,128 @@ func (nic *NetworkInterface) AddPublishedService(client SkytapClient, port int,
 		return nic, err
 	}
 
-	nic.PublishedServices = append(nic.PublishedServices, service)
+	nic.PublishedServices = append(nic.PublishedServices, *service)
 
 	log.WithField("publishedService", service).Info("Service Added")
 
 	return nic, err
 }
+
+/*
+ List the published services of this interface.
+*/
+func (nic *NetworkInterface) ListPublishedServices(client SkytapClient, envId, vmId string) ([]PublishedService, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(publishedServicesPath(envId, vmId, nic.Id))
+	}
+
+	services := []PublishedService{}
+	_, err := RunSkytapRequest(client, true, &services, listReq)
+	return services, err
+}
+
+/*
+ Return a published service of this interface by id.
+*/
+func (nic *NetworkInterface) GetPublishedService(client SkytapClient, serviceId string, envId, vmId string) (*PublishedService, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(publishedServicePath(envId, vmId, nic.Id, serviceId))
+	}
+
+	service := &PublishedService{}
+	_, err := RunSkytapRequest(client, true, service, getReq)
+	return service, err
+}
+
+/*
+ Change the internal port of a published service. Skytap may assign a new external endpoint.
+*/
+func (nic *NetworkInterface) UpdatePublishedService(client SkytapClient, serviceId string, port int, envId, vmId string) (*PublishedService, error) {
+	log.WithFields(log.Fields{"envId": envId, "vmId": vmId, "interfaceId": nic.Id, "serviceId": serviceId}).Info("Updating service")
+
+	service := &PublishedService{InternalPort: port}
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(publishedServicePath(envId, vmId, nic.Id, serviceId)).BodyJSON(service)
+	}
+
+	_, err := RunSkytapRequest(client, true, service, updateReq)
+	if err != nil {
+		return service, err
+	}
+
+	for i := range nic.PublishedServices {
+		if nic.PublishedServices[i].Id == serviceId {
+			nic.PublishedServices[i] = *service
+		}
+	}
+	return service, nil
+}
+
+/*
+ Remove a published service from this interface.
+*/
+func (nic *NetworkInterface) DeletePublishedService(client SkytapClient, serviceId string, envId, vmId string) error {
+	log.WithFields(log.Fields{"envId": envId, "vmId": vmId, "interfaceId": nic.Id, "serviceId": serviceId}).Info("Deleting service")
+
+	deleteReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(publishedServicePath(envId, vmId, nic.Id, serviceId))
+	}
+
+	_, err := RunSkytapRequest(client, true, nil, deleteReq)
+	if err != nil {
+		return err
+	}
+
+	remaining := []PublishedService{}
+	for _, service := range nic.PublishedServices {
+		if service.Id != serviceId {
+			remaining = append(remaining, service)
+		}
+	}
+	nic.PublishedServices = remaining
+	return nil
+}
+
+/*
+ External endpoint of a published service, with the VM and interface it maps to.
+*/
+type ServiceMapping struct {
+	VmId         string
+	VmName       string
+	InterfaceId  string
+	Hostname     string
+	InternalPort int
+	ExternalIp   string
+	ExternalPort int
+}
+
+/*
+ Return the external endpoint as host:port.
+*/
+func (m ServiceMapping) External() string {
+	return net.JoinHostPort(m.ExternalIp, strconv.Itoa(m.ExternalPort))
+}
+
+/*
+ Return the published service mappings of all VMs in this environment, as currently loaded.
+*/
+func (e *Environment) PublishedServiceMappings() []ServiceMapping {
+	mappings := []ServiceMapping{}
+	for _, vm := range e.Vms {
+		for _, nic := range vm.Interfaces {
+			for _, service := range nic.PublishedServices {
+				mappings = append(mappings, ServiceMapping{
+					VmId:         vm.Id,
+					VmName:       vm.Name,
+					InterfaceId:  nic.Id,
+					Hostname:     nic.Hostname,
+					InternalPort: service.InternalPort,
+					ExternalIp:   service.ExternalIp,
+					ExternalPort: service.ExternalPort,
+				})
+			}
+		}
+	}
+	return mappings
+}
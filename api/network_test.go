@@ -52,6 +52,9 @@ func TestCreateManualNetwork(t *testing.T) {
 	require.Equal(t, "API Network", net.Name)
 	require.Equal(t, "10.0.1.0/24", net.Subnet)
 	require.Equal(t, "10.0.1.254", net.Gateway)
+
+	_, err = CreateManualNetwork(client, "1", "API Network", "10.0.1.0/24", "10.0.2.1")
+	require.Error(t, err, "Should reject gateway outside subnet")
 }
 
 func TestDeleteNetwork(t *testing.T) {
@@ -135,13 +138,122 @@ func TestAttachVpn(t *testing.T) {
 }
 
 func TestAddPublishedService(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/configurations/1/vms/2/interfaces/nic-1/services.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"internal_port":22}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"22","internal_port":22,"external_ip":"services-uswest.skytap.com","external_port":26160}`)
+	})
+
+	nic := &NetworkInterface{Id: "nic-1", Hostname: "host-1"}
+	_, err := nic.AddPublishedService(client, 22, "1", "2")
+	require.NoError(t, err, "Error adding published service")
+	require.Len(t, nic.PublishedServices, 1)
+	require.Equal(t, 26160, nic.PublishedServices[0].ExternalPort)
+
+	env := &Environment{Vms: []*VirtualMachine{{Id: "2", Name: "vm", Interfaces: []*NetworkInterface{nic}}}}
+	mappings := env.PublishedServiceMappings()
+	require.Len(t, mappings, 1)
+	require.Equal(t, "services-uswest.skytap.com:26160", mappings[0].External())
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/configurations/1/vms/2/interfaces/nic-1/services/22.json", r.URL.Path)
+	})
+	err = nic.DeletePublishedService(client, "22", "1", "2")
+	require.NoError(t, err, "Error deleting published service")
+	require.Empty(t, nic.PublishedServices)
+}
+
+func TestListAndUpdateNetwork(t *testing.T) {
 	netJson := readJson(t, "testdata/network-1.json")
 
 	client := skytapClient(t)
-	server := getMockServerForString(client, netJson)
+	server := getMockServer(client)
 	defer server.Close()
 
 	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/configurations/1/networks.json", r.URL.Path)
+		fmt.Fprintf(w, "[%s]", netJson)
+	})
+	networks, err := ListNetworks(client, "1")
+	require.NoError(t, err, "Error listing networks")
+	require.Len(t, networks, 1)
+	require.Equal(t, "99", networks[0].Id)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/configurations/1/networks/99.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"name":"Renamed","primary_nameserver":"10.0.0.53","secondary_nameserver":""}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, netJson)
 	})
+	_, err = UpdateNetwork(client, "1", "99", &NetworkUpdate{Name: "Renamed", Nameservers: &[]string{"10.0.0.53"}})
+	require.NoError(t, err, "Error updating network")
 
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"primary_nameserver":"","secondary_nameserver":""}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, netJson)
+	})
+	_, err = UpdateNetwork(client, "1", "99", &NetworkUpdate{Nameservers: &[]string{}})
+	require.NoError(t, err, "Error clearing nameservers")
+
+	env := &Environment{Networks: networks}
+	require.Equal(t, "99", env.FindNetworkByName("Default Network").Id)
+	require.Nil(t, env.FindNetworkByName("Missing"))
+}
+
+func TestEnsureVpnConnected(t *testing.T) {
+	netJson := readJson(t, "testdata/network-1.json")
+	attachVpnJson := readJson(t, "testdata/attach-vpn-1.json")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	// Already attached and connected: no changes are made
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/configurations/1/networks/99.json", r.URL.Path)
+		fmt.Fprintln(w, netJson)
+	})
+	network := &Network{Id: "99"}
+	attachment, err := network.EnsureVpnConnected(client, "1", "vpn-1")
+	require.NoError(t, err, "Error ensuring VPN connection")
+	require.True(t, attachment.Connected)
+	require.Equal(t, "99-vpn-1", attachment.Id)
+
+	// Not attached: attach, connect, then report connected
+	attached := false
+	connected := false
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET":
+			if !attached {
+				fmt.Fprintln(w, `{"id":"99","vpn_attachments":[]}`)
+			} else {
+				fmt.Fprintf(w, `{"id":"99","vpn_attachments":[{"id":"99-vpn-2","connected":%t,"vpn":{"id":"vpn-2"}}]}`, connected)
+			}
+		case r.Method == "POST" && r.URL.Path == "/configurations/1/networks/99/vpns.json":
+			attached = true
+			fmt.Fprintln(w, attachVpnJson)
+		case r.Method == "PUT" && r.URL.Path == "/configurations/1/networks/99/vpns/vpn-2":
+			require.True(t, attached, "Should attach before connecting")
+			connected = true
+			fmt.Fprintln(w, attachVpnJson)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+	attachment, err = network.EnsureVpnConnected(client, "1", "vpn-2")
+	require.NoError(t, err, "Error ensuring VPN connection")
+	require.True(t, attachment.Connected)
+	require.Equal(t, "99-vpn-2", network.FindVpnAttachment("vpn-2").Id)
 }
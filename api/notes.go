@@ -0,0 +1,223 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	NotePath = "notes"
+)
+
+/*
+ Note on an environment, template or VM.
+*/
+type Note struct {
+	Id        string
+	Text      string
+	Author    NoteAuthor
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+/*
+ User who wrote a note.
+*/
+type NoteAuthor = User
+
+/*
+ Request body for note create and update commands.
+*/
+type NoteBody struct {
+	Text string `json:"text"`
+}
+
+func (n *Note) UnmarshalJSON(data []byte) error {
+	raw := struct {
+		Id        string `json:"id"`
+		Text      string `json:"text"`
+		User      User   `json:"user"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	createdAt, err := parseSkytapTime(raw.CreatedAt)
+	if err != nil {
+		return err
+	}
+	updatedAt, err := parseSkytapTime(raw.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	*n = Note{Id: raw.Id, Text: raw.Text, Author: raw.User, CreatedAt: createdAt, UpdatedAt: updatedAt}
+	return nil
+}
+
+// Paths for notes, relative to the owning resource (e.g. "configurations/1").
+func notesPath(ownerPath string) string { return fmt.Sprintf("%s/%s.json", ownerPath, NotePath) }
+func noteIdPath(ownerPath string, noteId string) string {
+	return fmt.Sprintf("%s/%s/%s.json", ownerPath, NotePath, noteId)
+}
+
+/*
+ List the notes of an environment.
+*/
+func (e *Environment) ListNotes(client SkytapClient) ([]Note, error) {
+	return listNotes(client, environmentIdV1Path(e.Id))
+}
+
+/*
+ Add a note to an environment.
+*/
+func (e *Environment) AddNote(client SkytapClient, text string) (*Note, error) {
+	log.WithFields(log.Fields{"envId": e.Id}).Info("Adding note to environment")
+
+	return addNote(client, environmentIdV1Path(e.Id), text)
+}
+
+/*
+ Replace the text of an existing environment note.
+*/
+func (e *Environment) UpdateNote(client SkytapClient, noteId string, text string) (*Note, error) {
+	log.WithFields(log.Fields{"envId": e.Id, "noteId": noteId}).Info("Updating environment note")
+
+	return updateNote(client, environmentIdV1Path(e.Id), noteId, text)
+}
+
+/*
+ Delete a note from an environment.
+*/
+func (e *Environment) DeleteNote(client SkytapClient, noteId string) error {
+	log.WithFields(log.Fields{"envId": e.Id, "noteId": noteId}).Info("Deleting environment note")
+
+	return deleteNote(client, environmentIdV1Path(e.Id), noteId)
+}
+
+/*
+ List the notes of a template.
+*/
+func (t *Template) ListNotes(client SkytapClient) ([]Note, error) {
+	return listNotes(client, templateIdV1Path(t.Id))
+}
+
+/*
+ Add a note to a template.
+*/
+func (t *Template) AddNote(client SkytapClient, text string) (*Note, error) {
+	log.WithFields(log.Fields{"templateId": t.Id}).Info("Adding note to template")
+
+	return addNote(client, templateIdV1Path(t.Id), text)
+}
+
+/*
+ Replace the text of an existing template note.
+*/
+func (t *Template) UpdateNote(client SkytapClient, noteId string, text string) (*Note, error) {
+	log.WithFields(log.Fields{"templateId": t.Id, "noteId": noteId}).Info("Updating template note")
+
+	return updateNote(client, templateIdV1Path(t.Id), noteId, text)
+}
+
+/*
+ Delete a note from a template.
+*/
+func (t *Template) DeleteNote(client SkytapClient, noteId string) error {
+	log.WithFields(log.Fields{"templateId": t.Id, "noteId": noteId}).Info("Deleting template note")
+
+	return deleteNote(client, templateIdV1Path(t.Id), noteId)
+}
+
+/*
+ List the notes of a VM.
+*/
+func (vm *VirtualMachine) ListNotes(client SkytapClient) ([]Note, error) {
+	return listNotes(client, vmIdPath(vm.Id))
+}
+
+/*
+ Add a note to a VM.
+*/
+func (vm *VirtualMachine) AddNote(client SkytapClient, text string) (*Note, error) {
+	log.WithFields(log.Fields{"vmId": vm.Id}).Info("Adding note to VM")
+
+	return addNote(client, vmIdPath(vm.Id), text)
+}
+
+/*
+ Replace the text of an existing VM note.
+*/
+func (vm *VirtualMachine) UpdateNote(client SkytapClient, noteId string, text string) (*Note, error) {
+	log.WithFields(log.Fields{"vmId": vm.Id, "noteId": noteId}).Info("Updating VM note")
+
+	return updateNote(client, vmIdPath(vm.Id), noteId, text)
+}
+
+/*
+ Delete a note from a VM.
+*/
+func (vm *VirtualMachine) DeleteNote(client SkytapClient, noteId string) error {
+	log.WithFields(log.Fields{"vmId": vm.Id, "noteId": noteId}).Info("Deleting VM note")
+
+	return deleteNote(client, vmIdPath(vm.Id), noteId)
+}
+
+func listNotes(client SkytapClient, ownerPath string) ([]Note, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(notesPath(ownerPath))
+	}
+
+	notes := []Note{}
+	_, err := RunSkytapRequest(client, false, &notes, listReq)
+	return notes, err
+}
+
+func addNote(client SkytapClient, ownerPath string, text string) (*Note, error) {
+	addReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(notesPath(ownerPath)).BodyJSON(&NoteBody{Text: text})
+	}
+
+	note := &Note{}
+	_, err := RunSkytapRequest(client, false, note, addReq)
+	return note, err
+}
+
+func updateNote(client SkytapClient, ownerPath string, noteId string, text string) (*Note, error) {
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(noteIdPath(ownerPath, noteId)).BodyJSON(&NoteBody{Text: text})
+	}
+
+	note := &Note{}
+	_, err := RunSkytapRequest(client, false, note, updateReq)
+	return note, err
+}
+
+func deleteNote(client SkytapClient, ownerPath string, noteId string) error {
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(noteIdPath(ownerPath, noteId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentNotes(t *testing.T) {
+	notesJson := readJson(t, "testdata/notes-1.json")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	env := &Environment{Id: "1"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/configurations/1/notes.json", r.URL.Path)
+		fmt.Fprintln(w, notesJson)
+	})
+
+	notes, err := env.ListNotes(client)
+	require.NoError(t, err, "Error listing notes")
+	require.Len(t, notes, 1)
+	require.Equal(t, "2925839", notes[0].Id)
+	require.Equal(t, "jane.doe@example.com", notes[0].Author.LoginName)
+	require.True(t, time.Date(2016, 12, 13, 19, 29, 56, 0, time.UTC).Equal(notes[0].CreatedAt))
+	require.True(t, notes[0].UpdatedAt.After(notes[0].CreatedAt))
+
+	noteJson := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(notesJson), "["), "]")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/configurations/1/notes.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"text":"Handing off"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, noteJson)
+	})
+
+	note, err := env.AddNote(client, "Handing off")
+	require.NoError(t, err, "Error adding note")
+	require.Equal(t, "2925839", note.Id)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/configurations/1/notes/2925839.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"text":"Taken over"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, strings.Replace(noteJson, "Handing off to the EU rotation, env is stable.", "Taken over", 1))
+	})
+
+	note, err = env.UpdateNote(client, note.Id, "Taken over")
+	require.NoError(t, err, "Error updating note")
+	require.Equal(t, "Taken over", note.Text)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/configurations/1/notes/2925839.json", r.URL.Path)
+	})
+
+	err = env.DeleteNote(client, note.Id)
+	require.NoError(t, err, "Error deleting note")
+}
+
+func TestVmNotes(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	vm := &VirtualMachine{Id: "1001"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/vms/1001/notes.json", r.URL.Path)
+		fmt.Fprintln(w, `[{"id":"1","text":"bad","created_at":"yesterday"}]`)
+	})
+
+	_, err := vm.ListNotes(client)
+	require.Error(t, err, "Should fail on unparseable timestamp")
+}
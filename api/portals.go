@@ -0,0 +1,186 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Sharing portals are called publish sets (published URLs) in the REST API.
+	SharingPortalPath = "publish_sets"
+
+	SharingPortalSingleUrl   = "single_url"
+	SharingPortalMultipleUrl = "multiple_url"
+
+	SharingPortalAccessUse       = "use"
+	SharingPortalAccessRunAndUse = "run_and_use"
+	SharingPortalAccessViewOnly  = "view_only"
+)
+
+/*
+ Sharing portal (published URL) of an environment.
+
+ Also used as request body for create and update commands, unset fields are left unchanged.
+*/
+type SharingPortal struct {
+	Id                   string            `json:"id,omitempty"`
+	Url                  string            `json:"url,omitempty"`
+	Name                 string            `json:"name,omitempty"`
+	PublishSetType       string            `json:"publish_set_type,omitempty"`
+	Vms                  []SharingPortalVm `json:"vms,omitempty"`
+	Password             *string           `json:"password,omitempty"`
+	ExpirationDate       *string           `json:"expiration_date,omitempty"`
+	RuntimeLimit         *int              `json:"runtime_limit,omitempty"`
+	RuntimeLeftInSeconds *int              `json:"runtime_left_in_seconds,omitempty"`
+	UseSmartClient       *bool             `json:"use_smart_client,omitempty"`
+	DesktopsUrl          string            `json:"desktops_url,omitempty"`
+}
+
+/*
+ VM in a sharing portal, along with the permissions granted to portal users.
+*/
+type SharingPortalVm struct {
+	Id         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	VmRef      string `json:"vm_ref,omitempty"`
+	Access     string `json:"access,omitempty"`
+	DesktopUrl string `json:"desktop_url,omitempty"`
+}
+
+/*
+ Select a VM for a sharing portal with the given access (e.g. SharingPortalAccessRunAndUse).
+*/
+func NewSharingPortalVm(vmId string, access string) SharingPortalVm {
+	return SharingPortalVm{VmRef: fmt.Sprintf("%s/%s", BaseUriV1, vmIdPath(vmId)), Access: access}
+}
+
+/*
+ The URL to hand out to portal users.
+*/
+func (p *SharingPortal) PortalUrl() string { return p.DesktopsUrl }
+
+/*
+ Set the password portal users must enter. An empty password removes it.
+*/
+func (p *SharingPortal) SetPassword(password string) { p.Password = &password }
+
+/*
+ Set the time after which the portal stops working.
+*/
+func (p *SharingPortal) SetExpiration(expires time.Time) {
+	formatted := expires.Format(SkytapTimeFormat)
+	p.ExpirationDate = &formatted
+}
+
+/*
+ Return the expiration of the portal, or the zero time if it does not expire.
+*/
+func (p *SharingPortal) Expiration() (time.Time, error) {
+	if p.ExpirationDate == nil {
+		return time.Time{}, nil
+	}
+	return parseSkytapTime(*p.ExpirationDate)
+}
+
+/*
+ Limit the total time, in minutes, the portal VMs may run.
+*/
+func (p *SharingPortal) SetRuntimeLimit(minutes int) { p.RuntimeLimit = &minutes }
+
+func sharingPortalsPath(envId string) string {
+	return fmt.Sprintf("%s/%s.json", environmentIdV1Path(envId), SharingPortalPath)
+}
+func sharingPortalIdPath(envId string, portalId string) string {
+	return fmt.Sprintf("%s/%s/%s.json", environmentIdV1Path(envId), SharingPortalPath, portalId)
+}
+
+/*
+ List the sharing portals of an environment.
+*/
+func (e *Environment) ListSharingPortals(client SkytapClient) ([]SharingPortal, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(sharingPortalsPath(e.Id))
+	}
+
+	portals := []SharingPortal{}
+	_, err := RunSkytapRequest(client, false, &portals, listReq)
+	return portals, err
+}
+
+/*
+ Return a sharing portal of an environment by id.
+*/
+func (e *Environment) GetSharingPortal(client SkytapClient, portalId string) (*SharingPortal, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(sharingPortalIdPath(e.Id, portalId))
+	}
+
+	portal := &SharingPortal{}
+	_, err := RunSkytapRequest(client, false, portal, getReq)
+	return portal, err
+}
+
+/*
+ Create a sharing portal in an environment. The result contains the portal URL, see PortalUrl.
+*/
+func (e *Environment) CreateSharingPortal(client SkytapClient, portal *SharingPortal) (*SharingPortal, error) {
+	log.WithFields(log.Fields{"envId": e.Id, "name": portal.Name}).Info("Creating sharing portal")
+
+	body := *portal
+	if body.PublishSetType == "" {
+		body.PublishSetType = SharingPortalSingleUrl
+	}
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(sharingPortalsPath(e.Id)).BodyJSON(&body)
+	}
+
+	created := &SharingPortal{}
+	_, err := RunSkytapRequest(client, false, created, createReq)
+	return created, err
+}
+
+/*
+ Update a sharing portal. Only the fields set in changes are sent.
+*/
+func (e *Environment) UpdateSharingPortal(client SkytapClient, portalId string, changes *SharingPortal) (*SharingPortal, error) {
+	log.WithFields(log.Fields{"envId": e.Id, "portalId": portalId}).Info("Updating sharing portal")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(sharingPortalIdPath(e.Id, portalId)).BodyJSON(changes)
+	}
+
+	updated := &SharingPortal{}
+	_, err := RunSkytapRequest(client, false, updated, updateReq)
+	return updated, err
+}
+
+/*
+ Delete a sharing portal from an environment.
+*/
+func (e *Environment) DeleteSharingPortal(client SkytapClient, portalId string) error {
+	log.WithFields(log.Fields{"envId": e.Id, "portalId": portalId}).Info("Deleting sharing portal")
+
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(sharingPortalIdPath(e.Id, portalId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
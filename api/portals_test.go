@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharingPortals(t *testing.T) {
+	envJson := readJson(t, "testdata/environment-1.json")
+
+	client := skytapClient(t)
+	server := getMockServerForString(client, envJson)
+	defer server.Close()
+
+	env, err := GetEnvironment(client, "1")
+	require.NoError(t, err, "Error getting environment")
+	require.Len(t, env.SharingPortals, 1)
+	portal := env.SharingPortals[0]
+	require.Equal(t, "2492910", portal.Id)
+	require.Equal(t, "https://cloud.skytap.com/vms/9570931149e0ac0d3de7d7d6a0af3a11/desktops", portal.PortalUrl())
+	require.Equal(t, SharingPortalAccessRunAndUse, portal.Vms[0].Access)
+	require.Nil(t, portal.Password)
+
+	portalJson := `{"id":"3","name":"Demo","publish_set_type":"single_url","desktops_url":"https://cloud.skytap.com/vms/abc/desktops",
+		"runtime_limit":120,"expiration_date":"2030/01/31 17:00:00 +0000","vms":[{"id":"5","vm_ref":"https://cloud.skytap.com/vms/1001","access":"use"}]}`
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/configurations/1/publish_sets.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"name":"Demo","publish_set_type":"single_url","vms":[{"vm_ref":"https://cloud.skytap.com/vms/1001","access":"use"}],`+
+			`"password":"secret","expiration_date":"2030/01/31 17:00:00 +0000","runtime_limit":120}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, portalJson)
+	})
+
+	request := &SharingPortal{Name: "Demo", Vms: []SharingPortalVm{NewSharingPortalVm("1001", SharingPortalAccessUse)}}
+	request.SetPassword("secret")
+	request.SetExpiration(time.Date(2030, 1, 31, 17, 0, 0, 0, time.UTC))
+	request.SetRuntimeLimit(120)
+
+	created, err := env.CreateSharingPortal(client, request)
+	require.NoError(t, err, "Error creating sharing portal")
+	require.Equal(t, "", request.PublishSetType, "Should not change the request")
+	require.Equal(t, "https://cloud.skytap.com/vms/abc/desktops", created.PortalUrl())
+	expires, err := created.Expiration()
+	require.NoError(t, err, "Error parsing expiration")
+	require.Equal(t, 2030, expires.Year())
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/configurations/1/publish_sets/3.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"password":""}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, portalJson)
+	})
+
+	changes := &SharingPortal{}
+	changes.SetPassword("")
+	_, err = env.UpdateSharingPortal(client, created.Id, changes)
+	require.NoError(t, err, "Error updating sharing portal")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/configurations/1/publish_sets/3.json", r.URL.Path)
+	})
+
+	err = env.DeleteSharingPortal(client, created.Id)
+	require.NoError(t, err, "Error deleting sharing portal")
+}
@@ -0,0 +1,268 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ProjectPath = "projects"
+
+	ProjectRoleViewer      = "viewer"
+	ProjectRoleParticipant = "participant"
+	ProjectRoleEditor      = "editor"
+	ProjectRoleManager     = "manager"
+)
+
+/*
+ Skytap project resource, groups environments and templates and controls who can access them.
+*/
+type Project struct {
+	Id                 string `json:"id,omitempty"`
+	Url                string `json:"url,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Summary            string `json:"summary,omitempty"`
+	AutoAddRoleName    string `json:"auto_add_role_name,omitempty"`
+	ShowProjectMembers *bool  `json:"show_project_members,omitempty"`
+	OwnerName          string `json:"owner_name,omitempty"`
+	OwnerUrl           string `json:"owner_url,omitempty"`
+	CreatedAt          string `json:"created_at,omitempty"`
+}
+
+/*
+ User who is a member of a project, with their project role.
+*/
+type ProjectMember struct {
+	Id        string `json:"id"`
+	Url       string `json:"url"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	LoginName string `json:"login_name"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+}
+
+/*
+ Request body for project membership commands.
+*/
+type ProjectRoleBody struct {
+	Role string `json:"role"`
+}
+
+func projectIdPath(projectId string) string { return fmt.Sprintf("%s/%s.json", ProjectPath, projectId) }
+func projectChildrenPath(projectId string, childPath string) string {
+	return fmt.Sprintf("%s/%s/%s.json", ProjectPath, projectId, childPath)
+}
+func projectChildIdPath(projectId string, childPath string, childId string) string {
+	return fmt.Sprintf("%s/%s/%s/%s.json", ProjectPath, projectId, childPath, childId)
+}
+
+/*
+ List all projects visible to the user.
+*/
+func ListProjects(client SkytapClient) ([]Project, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(ProjectPath + ".json")
+	}
+
+	projects := []Project{}
+	_, err := RunSkytapRequest(client, false, &projects, listReq)
+	return projects, err
+}
+
+/*
+ Return an existing project by id.
+*/
+func GetProject(client SkytapClient, projectId string) (*Project, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(projectIdPath(projectId))
+	}
+
+	project := &Project{}
+	_, err := RunSkytapRequest(client, false, project, getReq)
+	return project, err
+}
+
+/*
+ Create a new project.
+*/
+func CreateProject(client SkytapClient, name string, summary string) (*Project, error) {
+	log.WithFields(log.Fields{"name": name}).Info("Creating project")
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(ProjectPath + ".json").BodyJSON(&Project{Name: name, Summary: summary})
+	}
+
+	project := &Project{}
+	_, err := RunSkytapRequest(client, false, project, createReq)
+	return project, err
+}
+
+/*
+ Update a project. Only the fields set in changes are sent.
+*/
+func UpdateProject(client SkytapClient, projectId string, changes *Project) (*Project, error) {
+	log.WithFields(log.Fields{"projectId": projectId}).Info("Updating project")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(projectIdPath(projectId)).BodyJSON(changes)
+	}
+
+	project := &Project{}
+	_, err := RunSkytapRequest(client, false, project, updateReq)
+	return project, err
+}
+
+/*
+ Delete a project by id. Environments and templates in the project are not deleted.
+*/
+func DeleteProject(client SkytapClient, projectId string) error {
+	log.WithFields(log.Fields{"projectId": projectId}).Info("Deleting project")
+
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(projectIdPath(projectId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
+
+/*
+ List the environments in a project.
+*/
+func (p *Project) ListEnvironments(client SkytapClient) ([]Environment, error) {
+	return ListEnvironments(client, EnvironmentFilter{ProjectId: p.Id})
+}
+
+/*
+ Add an environment to a project.
+*/
+func (p *Project) AddEnvironment(client SkytapClient, envId string) error {
+	log.WithFields(log.Fields{"projectId": p.Id, "envId": envId}).Info("Adding environment to project")
+
+	return p.addChild(client, EnvironmentPath, envId)
+}
+
+/*
+ Remove an environment from a project. The environment itself is not deleted.
+*/
+func (p *Project) RemoveEnvironment(client SkytapClient, envId string) error {
+	log.WithFields(log.Fields{"projectId": p.Id, "envId": envId}).Info("Removing environment from project")
+
+	return p.removeChild(client, EnvironmentPath, envId)
+}
+
+/*
+ List the templates in a project.
+*/
+func (p *Project) ListTemplates(client SkytapClient) ([]Template, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(projectChildrenPath(p.Id, TemplatePath))
+	}
+
+	templates := []Template{}
+	_, err := RunSkytapRequest(client, false, &templates, listReq)
+	return templates, err
+}
+
+/*
+ Add a template to a project.
+*/
+func (p *Project) AddTemplate(client SkytapClient, templateId string) error {
+	log.WithFields(log.Fields{"projectId": p.Id, "templateId": templateId}).Info("Adding template to project")
+
+	return p.addChild(client, TemplatePath, templateId)
+}
+
+/*
+ Remove a template from a project. The template itself is not deleted.
+*/
+func (p *Project) RemoveTemplate(client SkytapClient, templateId string) error {
+	log.WithFields(log.Fields{"projectId": p.Id, "templateId": templateId}).Info("Removing template from project")
+
+	return p.removeChild(client, TemplatePath, templateId)
+}
+
+/*
+ List the members of a project.
+*/
+func (p *Project) ListMembers(client SkytapClient) ([]ProjectMember, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(projectChildrenPath(p.Id, UserPath))
+	}
+
+	members := []ProjectMember{}
+	_, err := RunSkytapRequest(client, false, &members, listReq)
+	return members, err
+}
+
+/*
+ Add a user to a project with the given role (e.g. ProjectRoleParticipant).
+*/
+func (p *Project) AddMember(client SkytapClient, userId string, role string) error {
+	log.WithFields(log.Fields{"projectId": p.Id, "userId": userId, "role": role}).Info("Adding member to project")
+
+	addReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(projectChildIdPath(p.Id, UserPath, userId)).BodyJSON(&ProjectRoleBody{Role: role})
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, addReq)
+	return err
+}
+
+/*
+ Change the role of an existing project member.
+*/
+func (p *Project) SetMemberRole(client SkytapClient, userId string, role string) error {
+	log.WithFields(log.Fields{"projectId": p.Id, "userId": userId, "role": role}).Info("Changing project member role")
+
+	roleReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(projectChildIdPath(p.Id, UserPath, userId)).BodyJSON(&ProjectRoleBody{Role: role})
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, roleReq)
+	return err
+}
+
+/*
+ Remove a user from a project.
+*/
+func (p *Project) RemoveMember(client SkytapClient, userId string) error {
+	log.WithFields(log.Fields{"projectId": p.Id, "userId": userId}).Info("Removing member from project")
+
+	return p.removeChild(client, UserPath, userId)
+}
+
+func (p *Project) addChild(client SkytapClient, childPath string, childId string) error {
+	addReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(projectChildIdPath(p.Id, childPath, childId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, addReq)
+	return err
+}
+
+func (p *Project) removeChild(client SkytapClient, childPath string, childId string) error {
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(projectChildIdPath(p.Id, childPath, childId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateProject(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/projects.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"name":"Acme","summary":"Acme engagement"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"5","url":"https://cloud.skytap.com/projects/5","name":"Acme","summary":"Acme engagement"}`)
+	})
+
+	project, err := CreateProject(client, "Acme", "Acme engagement")
+	require.NoError(t, err, "Error creating project")
+	require.Equal(t, "5", project.Id)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/projects/5.json", r.URL.Path)
+	})
+
+	err = DeleteProject(client, project.Id)
+	require.NoError(t, err, "Error deleting project")
+}
+
+func TestProjectEnvironments(t *testing.T) {
+	envJson := readJson(t, "testdata/environment-1.json")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	project := &Project{Id: "5"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/projects/5/configurations/1.json", r.URL.Path)
+	})
+
+	err := project.AddEnvironment(client, "1")
+	require.NoError(t, err, "Error adding environment to project")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/projects/5/configurations.json", r.URL.Path)
+		fmt.Fprintln(w, "["+envJson+"]")
+	})
+
+	envs, err := ListEnvironments(client, EnvironmentFilter{ProjectId: "5"})
+	require.NoError(t, err, "Error listing project environments")
+	require.Len(t, envs, 1)
+	require.Equal(t, "Environment 1", envs[0].Name)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/projects/5/templates/2.json", r.URL.Path)
+	})
+
+	err = project.RemoveTemplate(client, "2")
+	require.NoError(t, err, "Error removing template from project")
+}
+
+func TestProjectMembers(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	project := &Project{Id: "5"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/projects/5/users/15386.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"role":"participant"}`, strings.TrimSpace(string(body)))
+	})
+
+	err := project.AddMember(client, "15386", ProjectRoleParticipant)
+	require.NoError(t, err, "Error adding project member")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/projects/5/users.json", r.URL.Path)
+		fmt.Fprintln(w, `[{"id":"15386","login_name":"jane.doe@example.com","role":"participant"}]`)
+	})
+
+	members, err := project.ListMembers(client)
+	require.NoError(t, err, "Error listing project members")
+	require.Equal(t, ProjectRoleParticipant, members[0].Role)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/projects/5/users/15386.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"role":"manager"}`, strings.TrimSpace(string(body)))
+	})
+
+	err = project.SetMemberRole(client, "15386", ProjectRoleManager)
+	require.NoError(t, err, "Error changing project member role")
+}
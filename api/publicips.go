@@ -0,0 +1,150 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PublicIpPath = "ips"
+)
+
+/*
+ Request body for public IP acquire commands.
+*/
+type AcquirePublicIpBody struct {
+	Region string `json:"region"`
+}
+
+/*
+ Request body for public IP attach commands.
+*/
+type AttachPublicIpBody struct {
+	Ip string `json:"ip"`
+}
+
+func publicIpIdPath(ipId string) string { return fmt.Sprintf("%s/%s.json", PublicIpPath, ipId) }
+func interfacePublicIpsPath(envId string, vmId string, interfaceId string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s.json", EnvironmentPath, envId, VmPath, vmId, InterfacePath, interfaceId, PublicIpPath)
+}
+func interfacePublicIpPath(envId string, vmId string, interfaceId string, ip string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s/%s.json", EnvironmentPath, envId, VmPath, vmId, InterfacePath, interfaceId, PublicIpPath, ip)
+}
+
+/*
+ List the public IPs owned by the account, attached or not.
+*/
+func ListPublicIps(client SkytapClient) ([]PublicIp, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(PublicIpPath + ".json")
+	}
+
+	ips := []PublicIp{}
+	_, err := RunSkytapRequest(client, false, &ips, listReq)
+	return ips, err
+}
+
+/*
+ Return a public IP by id.
+*/
+func GetPublicIp(client SkytapClient, ipId string) (*PublicIp, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(publicIpIdPath(ipId))
+	}
+
+	ip := &PublicIp{}
+	_, err := RunSkytapRequest(client, false, ip, getReq)
+	return ip, err
+}
+
+/*
+ Acquire a new public IP in the given region, e.g. "US-West".
+*/
+func AcquirePublicIp(client SkytapClient, region string) (*PublicIp, error) {
+	log.WithFields(log.Fields{"region": region}).Info("Acquiring public IP")
+
+	acquireReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(PublicIpPath + "/acquire.json").BodyJSON(&AcquirePublicIpBody{Region: region})
+	}
+
+	ip := &PublicIp{}
+	_, err := RunSkytapRequest(client, false, ip, acquireReq)
+	return ip, err
+}
+
+/*
+ Release a public IP back to Skytap. The IP must be detached from all interfaces first.
+*/
+func ReleasePublicIp(client SkytapClient, ipId string) error {
+	log.WithFields(log.Fields{"ipId": ipId}).Info("Releasing public IP")
+
+	releaseReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(fmt.Sprintf("%s/%s/release.json", PublicIpPath, ipId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, releaseReq)
+	return err
+}
+
+/*
+ Attach an acquired public IP, given by address, to this interface.
+*/
+func (nic *NetworkInterface) AttachPublicIp(client SkytapClient, ip string, envId, vmId string) (*PublicIp, error) {
+	log.WithFields(log.Fields{"envId": envId, "vmId": vmId, "interfaceId": nic.Id, "ip": ip}).Info("Attaching public IP")
+
+	attachReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(interfacePublicIpsPath(envId, vmId, nic.Id)).BodyJSON(&AttachPublicIpBody{Ip: ip})
+	}
+
+	publicIp := &PublicIp{}
+	_, err := RunSkytapRequest(client, false, publicIp, attachReq)
+	if err != nil {
+		return publicIp, err
+	}
+
+	nic.PublicIps = append(nic.PublicIps, *publicIp)
+	nic.PublicIpsCount = len(nic.PublicIps)
+	return publicIp, nil
+}
+
+/*
+ Detach a public IP, given by address, from this interface. The IP stays acquired.
+*/
+func (nic *NetworkInterface) DetachPublicIp(client SkytapClient, ip string, envId, vmId string) error {
+	log.WithFields(log.Fields{"envId": envId, "vmId": vmId, "interfaceId": nic.Id, "ip": ip}).Info("Detaching public IP")
+
+	detachReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(interfacePublicIpPath(envId, vmId, nic.Id, ip))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, detachReq)
+	if err != nil {
+		return err
+	}
+
+	remaining := []PublicIp{}
+	for _, publicIp := range nic.PublicIps {
+		if publicIp.Address != ip && publicIp.Id != ip {
+			remaining = append(remaining, publicIp)
+		}
+	}
+	nic.PublicIps = remaining
+	nic.PublicIpsCount = len(nic.PublicIps)
+	return nil
+}
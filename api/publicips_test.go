@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicIpLifecycle(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	ipJson := `{"id":"ip-1","address":"203.0.113.7","region":"US-West","nics":[]}`
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/ips/acquire.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"region":"US-West"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, ipJson)
+	})
+	ip, err := AcquirePublicIp(client, "US-West")
+	require.NoError(t, err, "Error acquiring public IP")
+	require.Equal(t, "203.0.113.7", ip.Address)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/ips.json", r.URL.Path)
+		fmt.Fprintf(w, "[%s]", ipJson)
+	})
+	ips, err := ListPublicIps(client)
+	require.NoError(t, err, "Error listing public IPs")
+	require.Len(t, ips, 1)
+
+	nic := &NetworkInterface{Id: "nic-1"}
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/configurations/1/vms/2/interfaces/nic-1/ips.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"ip":"203.0.113.7"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, ipJson)
+	})
+	_, err = nic.AttachPublicIp(client, ip.Address, "1", "2")
+	require.NoError(t, err, "Error attaching public IP")
+	require.Equal(t, 1, nic.PublicIpsCount)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/configurations/1/vms/2/interfaces/nic-1/ips/203.0.113.7.json", r.URL.Path)
+	})
+	err = nic.DetachPublicIp(client, ip.Address, "1", "2")
+	require.NoError(t, err, "Error detaching public IP")
+	require.Empty(t, nic.PublicIps)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/ips/ip-1/release.json", r.URL.Path)
+	})
+	require.NoError(t, ReleasePublicIp(client, ip.Id), "Error releasing public IP")
+}
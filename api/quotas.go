@@ -0,0 +1,151 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	QuotaPath   = "quotas"
+	CompanyPath = "company"
+
+	QuotaConcurrentVms     = "concurrent_vms"
+	QuotaConcurrentSvms    = "concurrent_svms"
+	QuotaCumulativeSvms    = "cumulative_svms"
+	QuotaConcurrentStorage = "concurrent_storage_size"
+	QuotaPublicIps         = "public_ips"
+)
+
+/*
+ Usage and limit of a single quota, e.g. QuotaCumulativeSvms for SVM hours.
+*/
+type Quota struct {
+	Id    string   `json:"id"`
+	Usage float64  `json:"usage"`
+	Limit *float64 `json:"limit"`
+	Units string   `json:"units"`
+}
+
+/*
+ Request body entry for quota update commands.
+*/
+type QuotaLimit struct {
+	Id    string   `json:"id"`
+	Limit *float64 `json:"limit"`
+}
+
+/*
+ Returns true if the quota has no limit.
+*/
+func (q *Quota) Unlimited() bool { return q.Limit == nil }
+
+/*
+ Return the remaining quota, or -1 if the quota is unlimited.
+*/
+func (q *Quota) Remaining() float64 {
+	if q.Unlimited() {
+		return -1
+	}
+	return *q.Limit - q.Usage
+}
+
+/*
+ Return the quota with the given id, or nil if not present.
+*/
+func FindQuota(quotas []Quota, quotaId string) *Quota {
+	for i := range quotas {
+		if quotas[i].Id == quotaId {
+			return &quotas[i]
+		}
+	}
+	return nil
+}
+
+func quotasPath(ownerPath string) string { return fmt.Sprintf("%s/%s.json", ownerPath, QuotaPath) }
+
+/*
+ Return the quotas of the whole company.
+*/
+func GetCompanyQuotas(client SkytapClient) ([]Quota, error) {
+	return getQuotas(client, CompanyPath)
+}
+
+/*
+ Return the quotas of a user.
+*/
+func GetUserQuotas(client SkytapClient, userId string) ([]Quota, error) {
+	return getQuotas(client, UserPath+"/"+userId)
+}
+
+/*
+ Return the quotas of a department.
+*/
+func GetDepartmentQuotas(client SkytapClient, departmentId string) ([]Quota, error) {
+	return getQuotas(client, DepartmentPath+"/"+departmentId)
+}
+
+/*
+ Change quota limits of a department, keyed by quota id. A nil limit removes the limit.
+*/
+func UpdateDepartmentQuotas(client SkytapClient, departmentId string, limits map[string]*float64) ([]Quota, error) {
+	log.WithFields(log.Fields{"departmentId": departmentId, "limits": limits}).Info("Updating department quotas")
+
+	ids := []string{}
+	for id := range limits {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	body := []QuotaLimit{}
+	for _, id := range ids {
+		body = append(body, QuotaLimit{Id: id, Limit: limits[id]})
+	}
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(quotasPath(DepartmentPath + "/" + departmentId)).BodyJSON(body)
+	}
+
+	quotas := []Quota{}
+	_, err := RunSkytapRequest(client, false, &quotas, updateReq)
+	return quotas, err
+}
+
+/*
+ Return the quotas of this department.
+*/
+func (d *Department) GetQuotas(client SkytapClient) ([]Quota, error) {
+	return GetDepartmentQuotas(client, d.Id)
+}
+
+/*
+ Return the quotas of this user.
+*/
+func (u *User) GetQuotas(client SkytapClient) ([]Quota, error) {
+	return GetUserQuotas(client, u.Id)
+}
+
+func getQuotas(client SkytapClient, ownerPath string) ([]Quota, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(quotasPath(ownerPath))
+	}
+
+	quotas := []Quota{}
+	_, err := RunSkytapRequest(client, false, &quotas, getReq)
+	return quotas, err
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompanyQuotas(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/company/quotas.json", r.URL.Path)
+		fmt.Fprintln(w, `[{"id":"concurrent_vms","usage":4,"limit":10,"units":"VMs"},{"id":"cumulative_svms","usage":1200.5,"limit":null,"units":"SVM hours"}]`)
+	})
+
+	quotas, err := GetCompanyQuotas(client)
+	require.NoError(t, err, "Error getting company quotas")
+
+	vms := FindQuota(quotas, QuotaConcurrentVms)
+	require.Equal(t, float64(6), vms.Remaining())
+	svms := FindQuota(quotas, QuotaCumulativeSvms)
+	require.True(t, svms.Unlimited())
+	require.Equal(t, 1200.5, svms.Usage)
+	require.Nil(t, FindQuota(quotas, QuotaPublicIps))
+}
+
+func TestUpdateDepartmentQuotas(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/departments/8/quotas.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `[{"id":"concurrent_storage_size","limit":null},{"id":"concurrent_vms","limit":5}]`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `[{"id":"concurrent_vms","usage":0,"limit":5,"units":"VMs"}]`)
+	})
+
+	limit := float64(5)
+	quotas, err := UpdateDepartmentQuotas(client, "8", map[string]*float64{QuotaConcurrentVms: &limit, QuotaConcurrentStorage: nil})
+	require.NoError(t, err, "Error updating department quotas")
+	require.Equal(t, float64(5), *quotas[0].Limit)
+}
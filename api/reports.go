@@ -0,0 +1,283 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ReportPath = "reports"
+
+	// Report ranges are given without offset, see UsageReportRequest.Utc.
+	ReportTimeFormat = "2006/01/02 15:04:05"
+
+	// Pseudo runstate of a report whose results can be downloaded, see WaitUntilReady.
+	ReportStateReady = "ready"
+
+	ReportFormatCsv  = "csv"
+	ReportFormatJson = "json"
+
+	UsageResourceSvmHours      = "svm"
+	UsageResourceStorage       = "storage"
+	UsageResourceConcurrentVms = "concurrent_vm"
+	UsageResourcePublicIps     = "public_ip"
+
+	UsageAggregateByNone       = "none"
+	UsageAggregateByUser       = "user"
+	UsageAggregateByDepartment = "department"
+
+	UsageGroupByRaw   = "raw"
+	UsageGroupByDay   = "day"
+	UsageGroupByMonth = "month"
+)
+
+/*
+ Request body for usage report commands.
+*/
+type UsageReportRequest struct {
+	StartDate     string `json:"start_date"`
+	EndDate       string `json:"end_date"`
+	ResourceType  string `json:"resource_type"`
+	AggregateBy   string `json:"aggregate_by,omitempty"`
+	GroupBy       string `json:"group_by,omitempty"`
+	Region        string `json:"region,omitempty"`
+	ResultsFormat string `json:"results_format,omitempty"`
+	Utc           bool   `json:"utc"`
+}
+
+/*
+ Usage report, which is generated asynchronously. Poll with WaitUntilReady before downloading.
+*/
+type UsageReport struct {
+	Id            string `json:"id"`
+	Url           string `json:"url,omitempty"`
+	Ready         bool   `json:"ready"`
+	ResultsFormat string `json:"results_format,omitempty"`
+}
+
+/*
+ Single row of a downloaded usage report. Columns not mapped to a field are available in Fields.
+*/
+type UsageRow struct {
+	Date       string
+	User       string
+	Department string
+	Region     string
+	Usage      float64
+	Fields     map[string]string
+}
+
+/*
+ Set the covered period of the report. Times are formatted as UTC.
+*/
+func (r *UsageReportRequest) SetRange(start time.Time, end time.Time) {
+	r.StartDate, r.EndDate = reportRange(start, end)
+	r.Utc = true
+}
+
+// Start and end dates of a report request covering the given period, formatted as UTC.
+func reportRange(start time.Time, end time.Time) (string, string) {
+	return start.UTC().Format(ReportTimeFormat), end.UTC().Format(ReportTimeFormat)
+}
+
+func reportIdPath(reportId string) string { return fmt.Sprintf("%s/%s.json", ReportPath, reportId) }
+
+/*
+ Request generation of a usage report. The returned report is usually not ready yet.
+*/
+func RequestUsageReport(client SkytapClient, request *UsageReportRequest) (*UsageReport, error) {
+	log.WithFields(log.Fields{"request": request}).Info("Requesting usage report")
+
+	if request.StartDate == "" || request.EndDate == "" || request.ResourceType == "" {
+		return nil, errors.New("Usage report requires a start date, end date and resource type")
+	}
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(ReportPath + ".json").BodyJSON(request)
+	}
+
+	report := &UsageReport{}
+	_, err := RunSkytapRequest(client, false, report, createReq)
+	return report, err
+}
+
+/*
+ Return a usage report by id.
+*/
+func GetUsageReport(client SkytapClient, reportId string) (*UsageReport, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(reportIdPath(reportId))
+	}
+
+	report := &UsageReport{}
+	_, err := RunSkytapRequest(client, false, report, getReq)
+	return report, err
+}
+
+func (r *UsageReport) RunstateStr() string {
+	return reportRunstate(r.Ready)
+}
+
+func (r *UsageReport) Refresh(client SkytapClient) (RunstateAwareResource, error) {
+	return GetUsageReport(client, r.Id)
+}
+
+/*
+ Wait until the report has been generated.
+*/
+func (r *UsageReport) WaitUntilReady(client SkytapClient) (*UsageReport, error) {
+	ready, err := waitUntilReportReady(client, r)
+	return ready.(*UsageReport), err
+}
+
+// Pseudo runstate of asynchronously generated reports.
+func reportRunstate(ready bool) string {
+	if ready {
+		return ReportStateReady
+	}
+	return RunStateBusy
+}
+
+// Poll a usage or audit report until it reaches ReportStateReady.
+func waitUntilReportReady(client SkytapClient, r RunstateAwareResource) (RunstateAwareResource, error) {
+	return WaitUntilInState(client, []string{ReportStateReady}, r, false)
+}
+
+/*
+ Download the raw report results, in the requested format. The report must be ready.
+*/
+func (r *UsageReport) Download(client SkytapClient) ([]byte, error) {
+	if !r.Ready || r.Url == "" {
+		return nil, fmt.Errorf("Usage report %s is not ready for download", r.Id)
+	}
+	return GetSkytapRawResource(client, r.Url)
+}
+
+/*
+ Download the report results and parse them into rows.
+*/
+func (r *UsageReport) Rows(client SkytapClient) ([]UsageRow, error) {
+	data, err := r.Download(client)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUsageReport(data)
+}
+
+/*
+ Parse usage report results, either CSV with a header row or a JSON array of objects.
+*/
+func ParseUsageReport(data []byte) ([]UsageRow, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return []UsageRow{}, nil
+	}
+
+	records := []map[string]string{}
+	if trimmed[0] == '[' {
+		objects := []map[string]interface{}{}
+		if err := json.Unmarshal(trimmed, &objects); err != nil {
+			return nil, fmt.Errorf("Unable to parse JSON usage report: %s", err)
+		}
+		for _, object := range objects {
+			record := map[string]string{}
+			for key, value := range object {
+				if value != nil {
+					record[key] = fmt.Sprint(value)
+				}
+			}
+			records = append(records, record)
+		}
+	} else {
+		lines, err := csv.NewReader(bytes.NewReader(trimmed)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse CSV usage report: %s", err)
+		}
+		for _, line := range lines[1:] {
+			record := map[string]string{}
+			for i, value := range line {
+				if i < len(lines[0]) {
+					record[lines[0][i]] = value
+				}
+			}
+			records = append(records, record)
+		}
+	}
+
+	rows := []UsageRow{}
+	for _, record := range records {
+		row, err := usageRowFromRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Column names, normalized by normalizeColumn, that map to UsageRow fields.
+var usageDateColumns = []string{"date", "start_date", "period", "month", "day"}
+var usageUserColumns = []string{"user", "login_name", "user_name", "username"}
+var usageDepartmentColumns = []string{"department", "department_name"}
+var usageRegionColumns = []string{"region"}
+var usageValueColumns = []string{"usage", "svm_hours", "svms", "storage", "storage_size", "concurrent_vms", "public_ips", "value", "total"}
+
+func usageRowFromRecord(record map[string]string) (UsageRow, error) {
+	row := UsageRow{Fields: record}
+	columns := make(map[string]string, len(record))
+	for column := range record {
+		columns[normalizeColumn(column)] = column
+	}
+
+	row.Date, _ = firstUsageColumn(record, columns, usageDateColumns)
+	row.User, _ = firstUsageColumn(record, columns, usageUserColumns)
+	row.Department, _ = firstUsageColumn(record, columns, usageDepartmentColumns)
+	row.Region, _ = firstUsageColumn(record, columns, usageRegionColumns)
+
+	value, column := firstUsageColumn(record, columns, usageValueColumns)
+	if value != "" {
+		usage, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return row, fmt.Errorf("Unable to parse usage value '%s' in column '%s'", value, column)
+		}
+		row.Usage = usage
+	}
+	return row, nil
+}
+
+// Value and name of the first candidate column present in the record with a non-empty value, candidates are in priority order.
+func firstUsageColumn(record map[string]string, columns map[string]string, candidates []string) (string, string) {
+	for _, candidate := range candidates {
+		if column, ok := columns[candidate]; ok && record[column] != "" {
+			return record[column], column
+		}
+	}
+	return "", ""
+}
+
+func normalizeColumn(column string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(column)), " ", "_", -1)
+}
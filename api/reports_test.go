@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageReport(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/reports.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"start_date":"2030/01/01 00:00:00","end_date":"2030/02/01 00:00:00","resource_type":"svm","aggregate_by":"department",`+
+			`"results_format":"csv","utc":true}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"12","ready":false}`)
+	})
+
+	request := &UsageReportRequest{ResourceType: UsageResourceSvmHours, AggregateBy: UsageAggregateByDepartment, ResultsFormat: ReportFormatCsv}
+	request.SetRange(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	report, err := RequestUsageReport(client, request)
+	require.NoError(t, err, "Error requesting usage report")
+	_, err = report.Download(client)
+	require.Error(t, err, "Should not download a report that isn't ready")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/reports/12.json":
+			fmt.Fprintf(w, `{"id":"12","ready":true,"url":"%s/download/12.csv"}`, server.URL)
+		case "/download/12.csv":
+			fmt.Fprint(w, "Month,Department,Region,SVM Hours\n2030/01,Engineering,US-West,1200.5\n2030/01,Finance,US-West,3\n")
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	})
+
+	report, err = report.WaitUntilReady(client)
+	require.NoError(t, err, "Error waiting for usage report")
+
+	rows, err := report.Rows(client)
+	require.NoError(t, err, "Error downloading usage report")
+	require.Len(t, rows, 2)
+	require.Equal(t, "Engineering", rows[0].Department)
+	require.Equal(t, "2030/01", rows[0].Date)
+	require.Equal(t, 1200.5, rows[0].Usage)
+	require.Equal(t, "US-West", rows[1].Fields["Region"])
+}
+
+func TestParseUsageReportJson(t *testing.T) {
+	rows, err := ParseUsageReport([]byte(`[{"date":"2030/01/01","user":"jane.doe@example.com","usage":2.5,"vm_count":3}]`))
+	require.NoError(t, err, "Error parsing usage report")
+	require.Equal(t, "jane.doe@example.com", rows[0].User)
+	require.Equal(t, 2.5, rows[0].Usage)
+	require.Equal(t, "3", rows[0].Fields["vm_count"])
+
+	_, err = ParseUsageReport([]byte("user,usage\njane,lots\n"))
+	require.Error(t, err, "Should fail on non-numeric usage")
+}
+
+func TestParseUsageReportColumnPriority(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		rows, err := ParseUsageReport([]byte("Total,SVM Hours,Month,Date\n10,7.5,2030/01,2030/01/01\n"))
+		require.NoError(t, err, "Error parsing usage report")
+		require.Equal(t, 7.5, rows[0].Usage)
+		require.Equal(t, "2030/01/01", rows[0].Date)
+	}
+}
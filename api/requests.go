@@ -15,14 +15,18 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"time"
 
 	"encoding/json"
 
+	"github.com/YojimboSecurity/skytap-sdk-go/metadata"
 	"github.com/dghubble/sling"
 	log "github.com/sirupsen/logrus"
 )
@@ -43,6 +47,12 @@ const (
 */
 var baseUrlOveride = ""
 
+/*
+ Polling interval and number of polls of WaitUntilInState, variables for testability.
+*/
+var waitPeriod = 10 * time.Second
+var maxBusyWaitPeriods = 20
+
 /*
  General skytap json error response.
 */
@@ -52,6 +62,8 @@ type SkytapApiError struct {
 
 /*
  Skytap metadata service response.
+
+ Deprecated: use GetMetadata or the metadata package, which model the complete document.
 */
 type SkytapMetadata struct {
 	Id string `json:"id"`
@@ -71,6 +83,10 @@ type SkytapCredentials struct {
 type SkytapClient struct {
 	HttpClient  *http.Client
 	Credentials SkytapCredentials
+
+	// Optional hook called when WaitUntilInState gives up, with the last representation of the resource and the
+	// timeout error, e.g. ScreenshotOnTimeout. Errors of the hook can't change the result of the wait and should be logged.
+	OnWaitTimeout func(client SkytapClient, r RunstateAwareResource, err error)
 }
 
 /*
@@ -84,7 +100,7 @@ func NewSkytapClient(username string, apiKey string) *SkytapClient {
  Create a new client from credentials
 */
 func NewSkytapClientFromCredentials(credentials SkytapCredentials) *SkytapClient {
-	return &SkytapClient{&http.Client{}, credentials}
+	return &SkytapClient{HttpClient: &http.Client{}, Credentials: credentials}
 }
 
 /*
@@ -128,8 +144,6 @@ func WaitUntilInState(client SkytapClient, desiredStates []string, r RunstateAwa
 
 	hasChanged := !requireStateChange || current.RunstateStr() != r.RunstateStr()
 
-	maxBusyWaitPeriods := 20
-	waitPeriod := 10 * time.Second
 	for i := 0; i < maxBusyWaitPeriods && !(hasChanged && stringInSlice(current.RunstateStr(), desiredStates)); i++ {
 		time.Sleep(waitPeriod)
 		current, err = r.Refresh(client)
@@ -139,7 +153,11 @@ func WaitUntilInState(client SkytapClient, desiredStates []string, r RunstateAwa
 		hasChanged = hasChanged || current.RunstateStr() != r.RunstateStr()
 	}
 	if !stringInSlice(current.RunstateStr(), desiredStates) {
-		return current, errors.New(fmt.Sprintf("Didn't achieve any desired runstate in %s after %d seconds, resource is in runstate %s", desiredStates, time.Now().Unix()-start.Unix(), current.RunstateStr()))
+		err = errors.New(fmt.Sprintf("Didn't achieve any desired runstate in %s after %d seconds, resource is in runstate %s", desiredStates, time.Now().Unix()-start.Unix(), current.RunstateStr()))
+		if client.OnWaitTimeout != nil {
+			client.OnWaitTimeout(client, current, err)
+		}
+		return current, err
 	}
 	return current, err
 }
@@ -154,7 +172,9 @@ func WaitUntilInState(client SkytapClient, desiredStates []string, r RunstateAwa
  slingDecorator - Decorate request with specifics, set request path relative to root, add body, etc.
 */
 func RunSkytapRequest(client SkytapClient, useV2 bool, respJson interface{}, slingDecorator SlingDecorator) (*http.Response, error) {
-	return runSkytapRequestWithRetry(client, useV2, respJson, slingDecorator, 0)
+	return retryBusy(func() (*http.Request, *http.Response, error) {
+		return runSkytapRequestOnce(client, useV2, respJson, slingDecorator)
+	})
 }
 
 /*
@@ -168,21 +188,166 @@ func GetSkytapResource(client SkytapClient, url string, respObj interface{}) (*h
 }
 
 /*
-  Runs a skytap API request attempt, retry number as specified by retryNum.
+ Return the raw body of a skytap resource specified as complete GET based URL, e.g. a report download.
+*/
+func GetSkytapRawResource(client SkytapClient, url string) ([]byte, error) {
+	fromUrl := func(s *sling.Sling) *sling.Sling {
+		return s.New().Base(url).ResponseDecoder(rawResponseDecoder{})
+	}
+	body := []byte{}
+	_, err := RunSkytapRequest(client, false, &body, fromUrl)
+	return body, err
+}
+
+/*
+ Open a skytap resource specified as complete GET based URL for streaming, e.g. a large report download.
 
+ The caller must close the returned body. Busy responses are retried like RunSkytapRequest does.
 */
-func runSkytapRequestWithRetry(client SkytapClient, useV2 bool, respObj interface{}, slingDecorator SlingDecorator, retryNum int) (*http.Response, error) {
-	baseUrl := BaseUriV1
-	if baseUrlOveride != "" {
-		baseUrl = baseUrlOveride
-	} else if useV2 {
-		baseUrl = BaseUriV2
+func OpenSkytapResource(client SkytapClient, url string) (io.ReadCloser, error) {
+	req, err := sling.New().Get(url).Request()
+	if err != nil {
+		return nil, err
+	}
+	setRequestHeaders(client, req, false)
+
+	resp, err := doSkytapRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	if !isOkStatus(resp.StatusCode) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Received error status code calling SkyTap API: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+/*
+ Send a prepared request without decoding the response, retrying busy responses like RunSkytapRequest does.
+
+ Only requests without a body can be retried. The caller must close the returned body.
+*/
+func doSkytapRequest(client SkytapClient, req *http.Request) (*http.Response, error) {
+	httpClient := client.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := retryBusy(func() (*http.Request, *http.Response, error) {
+		resp, err := httpClient.Do(req)
+		return req, resp, err
+	})
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+/*
+ Run attempt until it doesn't get a busy response, at most maxRetries times more, waiting as told by Retry-After.
+
+ Waiting stops when the context of the attempted request is done. Returns the last response and error.
+*/
+func retryBusy(attempt func() (*http.Request, *http.Response, error)) (*http.Response, error) {
+	for retryNum := 0; ; retryNum++ {
+		req, resp, err := attempt()
+		if resp == nil || !isBusy(resp.StatusCode) {
+			return resp, err
+		}
+		resp.Body.Close()
+		if retryNum >= maxRetries {
+			log.WithFields(log.Fields{"url": req.URL, "maxRetries": maxRetries, "error": err}).Error("Maximum retries reached")
+			return resp, fmt.Errorf("Maximum retries (%d) reached calling %s(%s), resource is still busy", maxRetries, req.Method, req.URL)
+		}
+		retrySecs := retryAfterSecs(resp)
+		log.WithFields(log.Fields{
+			"method":         req.Method,
+			"url":            req.URL,
+			"retryNum":       retryNum,
+			"retryAfterSecs": retrySecs,
+		}).Info("Got resource busy response, retrying")
+		select {
+		case <-req.Context().Done():
+			return resp, req.Context().Err()
+		case <-time.After(time.Duration(retrySecs) * time.Second):
+		}
+	}
+}
+
+// Seconds to wait before retrying a busy response, from its Retry-After header or 10 by default.
+func retryAfterSecs(resp *http.Response) int {
+	after := resp.Header.Get("Retry-After")
+	if after == "" {
+		return 10
+	}
+	parsedSecs, err := strconv.ParseInt(after, 10, 32)
+	if err != nil {
+		log.Warnf("Couldn't parse Retry-After (%s)", after)
+		return 10
+	}
+	return int(parsedSecs)
+}
+
+/*
+ Response decoder for non-JSON resources. Bodies are copied as is into *[]byte targets, anything else is decoded as JSON.
+*/
+type rawResponseDecoder struct{}
+
+func (d rawResponseDecoder) Decode(resp *http.Response, v interface{}) error {
+	if raw, ok := v.(*[]byte); ok {
+		body, err := ioutil.ReadAll(resp.Body)
+		*raw = body
+		return err
 	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
 
-	base := sling.New().Base(baseUrl + "/").Client(client.HttpClient)
+/*
+ Runs a single skytap API request attempt. Busy responses are returned as is, for retryBusy to handle.
+*/
+func runSkytapRequestOnce(client SkytapClient, useV2 bool, respObj interface{}, slingDecorator SlingDecorator) (*http.Request, *http.Response, error) {
+	base := sling.New().Base(apiBaseUrl(useV2) + "/").Client(client.HttpClient)
 	s := slingDecorator(base)
 	skytapError := &SkytapApiError{}
 	req, err := s.Request()
+	if err != nil {
+		return req, nil, err
+	}
+	setRequestHeaders(client, req, useV2)
+	resp, err := s.Do(req, respObj, skytapError)
+
+	returnError := err
+	logRequestResponse(req, resp, respObj, returnError)
+
+	if resp != nil && !isOkStatus(resp.StatusCode) && !isBusy(resp.StatusCode) {
+		if skytapError.Error != "" {
+			returnError = errors.New(skytapError.Error)
+		} else if err == nil {
+			returnError = fmt.Errorf("Received error status code calling SkyTap API, but no additional error info: %s", resp.Status)
+			logRequestResponse(req, resp, respObj, returnError)
+		}
+	}
+	return req, resp, returnError
+}
+
+/*
+ Root of API paths, without trailing slash.
+*/
+func apiBaseUrl(useV2 bool) string {
+	if baseUrlOveride != "" {
+		return baseUrlOveride
+	} else if useV2 {
+		return BaseUriV2
+	}
+	return BaseUriV1
+}
+
+/*
+ Add credentials and the headers the API expects to a request.
+*/
+func setRequestHeaders(client SkytapClient, req *http.Request, useV2 bool) {
 	req.SetBasicAuth(client.Credentials.Username, client.Credentials.ApiKey)
 	acceptHeader := AcceptHeaderV1
 	if useV2 {
@@ -190,49 +355,19 @@ func runSkytapRequestWithRetry(client SkytapClient, useV2 bool, respObj interfac
 	}
 	req.Header.Set("Accept", acceptHeader)
 	req.Header.Set("User-Agent", UserAgent)
-	var resp *http.Response
-	resp, err = s.Do(req, respObj, skytapError)
-
-	returnError := err
-	logRequestResponse(req, resp, respObj, returnError)
+}
 
-	if !isOkStatus(resp.StatusCode) {
-		if isBusy(resp.StatusCode) {
-			retrySecs := 10
-			after := resp.Header.Get("Retry-After")
-			if after != "" {
-				parsedSecs, parseErr := strconv.ParseInt(after, 10, 32)
-				if parseErr != nil {
-					log.Warnf("Couldn't parse Retry-After (%s)", after)
-				} else {
-					retrySecs = int(parsedSecs)
-				}
-			} else if retryNum <= maxRetries {
-				log.WithFields(log.Fields{
-					"method":         req.Method,
-					"url":            req.URL,
-					"retryNum":       retryNum,
-					"retryAfterSecs": retrySecs,
-				}).Info("Got resource busy response, retrying")
-				time.Sleep(time.Duration(retrySecs) * time.Second)
-				runSkytapRequestWithRetry(client, useV2, respObj, slingDecorator, retryNum+1)
-			} else {
-				log.WithFields(log.Fields{"url": req.URL, "maxRetries": maxRetries, "error": err}).Error("Maximum retries reached")
-				returnError = errors.New(fmt.Sprintf("Maximum retries (%d) reached calling %s(%s), resource is still busy", maxRetries, req.Method, req.URL))
-			}
-		} else {
-			if skytapError.Error != "" {
-				returnError = errors.New(skytapError.Error)
-			} else if err == nil {
-				returnError = fmt.Errorf("Received error status code calling SkyTap API, but no additional error info: %s", resp.Status)
-				logRequestResponse(req, resp, respObj, returnError)
-			}
-		}
-	}
-	return resp, returnError
+/*
+ Implemented by response objects holding secrets, logRequestResponse logs the returned value instead.
+*/
+type redactedLogger interface {
+	redactedForLog() interface{}
 }
 
 func logRequestResponse(req *http.Request, resp *http.Response, respObj interface{}, err error) {
+	if redacted, ok := respObj.(redactedLogger); ok {
+		respObj = redacted.redactedForLog()
+	}
 
 	jsonStr, err := json.Marshal(respObj)
 	if err != nil {
@@ -263,16 +398,32 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
+/*
+ Returns true if the process is running inside a Skytap VM, i.e. the metadata service answers with a valid document.
+
+ Deprecated: use metadata.IsRunningInSkytap, or GetMetadata to use the HTTP settings of a client.
+*/
 func IsRunningInSkytap() bool {
-	skytapError := &SkytapApiError{}
-	response := &SkytapMetadata{}
+	return metadata.IsRunningInSkytap(context.Background())
+}
+
+/*
+ Fetch the metadata document of the VM this process runs in, using the client's HTTP settings.
+
+ Only the HTTP client is used, the metadata service needs no credentials. Documents are not cached, unless the client
+ has no HTTP client and metadata.DefaultClient is used. Hold a metadata.Client to reuse documents across calls.
+*/
+func GetMetadata(client SkytapClient) (*metadata.Metadata, error) {
+	m := metadata.DefaultClient
+	if client.HttpClient != nil {
+		m = metadata.NewClient()
+		m.Endpoint = MetadataUri
+		m.HttpClient = client.HttpClient
+	}
 
-	client := sling.New().Client(nil)
-	req, err := sling.New().Get(MetadataUri).Request()
-	resp, err := client.Do(req, response, skytapError)
+	md, err := m.Get(context.Background())
 	if err != nil {
-		log.Errorf("Failure calling Metadata Service (resp, err), %s, %s", resp, err)
-		return false
+		log.WithFields(log.Fields{"error": err}).Error("Failure calling Metadata Service")
 	}
-	return true
+	return md, err
 }
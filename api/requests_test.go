@@ -1 +1,86 @@
 package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingTransport struct {
+	body     string
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return &http.Response{
+		StatusCode:    200,
+		Status:        "200 OK",
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(t.body)),
+		ContentLength: int64(len(t.body)),
+		Request:       req,
+	}, nil
+}
+
+func TestGetMetadataUsesHttpClient(t *testing.T) {
+	transport := &countingTransport{body: `{"id":1001,"name":"Ubuntu VM"}`}
+	client := SkytapClient{HttpClient: &http.Client{Transport: transport}}
+
+	md, err := GetMetadata(client)
+	require.NoError(t, err, "Error getting metadata")
+	require.Equal(t, "1001", string(md.Id))
+
+	require.Equal(t, 1, transport.requests, "Should use the client's HTTP client")
+}
+
+func TestOpenSkytapResourceRetriesBusy(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	requests := 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(423)
+			return
+		}
+		w.Write([]byte("report"))
+	})
+
+	// Without an HTTP client the default one is used
+	client.HttpClient = nil
+	body, err := OpenSkytapResource(client, server.URL+"/download/1.csv")
+	require.NoError(t, err, "Error opening resource")
+	defer body.Close()
+	data, _ := ioutil.ReadAll(body)
+	require.Equal(t, "report", string(data))
+	require.Equal(t, 2, requests)
+}
+
+func TestRunSkytapRequestRetriesBusy(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	requests := 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(423)
+			return
+		}
+		w.Write([]byte("date,usage\n2030/01,1\n"))
+	})
+
+	body, err := GetSkytapRawResource(client, server.URL+"/download/1.csv")
+	require.NoError(t, err, "Error getting resource")
+	require.Equal(t, "date,usage\n2030/01,1\n", string(body))
+	require.Equal(t, 2, requests)
+}
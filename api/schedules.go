@@ -0,0 +1,287 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	SchedulePath = "schedules"
+
+	// Start and end times of a schedule are local to its time zone, so carry no offset.
+	ScheduleTimeFormat = "2006/01/02 15:04:05"
+
+	ScheduleActionStart    = "run"
+	ScheduleActionSuspend  = "suspend"
+	ScheduleActionStop     = "shutdown"
+	ScheduleActionPowerOff = "power_off"
+
+	ScheduleMonday    = "monday"
+	ScheduleTuesday   = "tuesday"
+	ScheduleWednesday = "wednesday"
+	ScheduleThursday  = "thursday"
+	ScheduleFriday    = "friday"
+	ScheduleSaturday  = "saturday"
+	ScheduleSunday    = "sunday"
+)
+
+/*
+ Schedule of recurring runstate actions on an environment or template.
+*/
+type Schedule struct {
+	Id            string           `json:"id,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	EnvironmentId string           `json:"configuration_id,omitempty"`
+	TemplateId    string           `json:"template_id,omitempty"`
+	TimeZone      string           `json:"time_zone,omitempty"`
+	StartAt       string           `json:"start_at,omitempty"`
+	EndAt         string           `json:"end_at,omitempty"`
+	DeleteAtEnd   bool             `json:"delete_at_end,omitempty"`
+	RecurringDays []string         `json:"recurring_days,omitempty"`
+	Actions       []ScheduleAction `json:"actions,omitempty"`
+}
+
+/*
+ Action run on each recurring day of a schedule.
+
+ Offset is the number of seconds after midnight, in the schedule's time zone.
+*/
+type ScheduleAction struct {
+	Id     string                `json:"id,omitempty"`
+	Type   string                `json:"type"`
+	Offset int                   `json:"offset"`
+	Params *ScheduleActionParams `json:"params,omitempty"`
+}
+
+/*
+ Restricts a schedule action to specific VMs. Without params the action applies to the whole environment.
+*/
+type ScheduleActionParams struct {
+	VmIds []string `json:"vm_ids,omitempty"`
+}
+
+var scheduleActionTypes = []string{ScheduleActionStart, ScheduleActionSuspend, ScheduleActionStop, ScheduleActionPowerOff}
+var scheduleDays = []string{ScheduleMonday, ScheduleTuesday, ScheduleWednesday, ScheduleThursday, ScheduleFriday, ScheduleSaturday, ScheduleSunday}
+
+/*
+ Create an action of the given type at hour:minute each recurring day. If vmIds are given, only those VMs are affected.
+*/
+func NewScheduleAction(actionType string, hour int, minute int, vmIds ...string) ScheduleAction {
+	action := ScheduleAction{Type: actionType, Offset: hour*3600 + minute*60}
+	if len(vmIds) > 0 {
+		action.Params = &ScheduleActionParams{VmIds: vmIds}
+	}
+	return action
+}
+
+/*
+ Set the date and time the schedule becomes active. Only the wall clock time is used, see TimeZone.
+*/
+func (sc *Schedule) SetStart(start time.Time) { sc.StartAt = start.Format(ScheduleTimeFormat) }
+
+/*
+ Set the date and time the schedule ends. Only the wall clock time is used, see TimeZone.
+*/
+func (sc *Schedule) SetEnd(end time.Time) { sc.EndAt = end.Format(ScheduleTimeFormat) }
+
+/*
+ Check the schedule for errors the API would reject it for.
+*/
+func (sc *Schedule) Validate() error {
+	if (sc.EnvironmentId == "") == (sc.TemplateId == "") {
+		return errors.New("Schedule must belong to exactly one environment or template")
+	}
+	if sc.Title == "" {
+		return errors.New("Schedule title is required")
+	}
+	if sc.TimeZone == "" {
+		return errors.New("Schedule time zone is required")
+	}
+
+	start, err := time.Parse(ScheduleTimeFormat, sc.StartAt)
+	if err != nil {
+		return fmt.Errorf("Schedule start '%s' is not in format %s", sc.StartAt, ScheduleTimeFormat)
+	}
+	if sc.EndAt != "" {
+		end, err := time.Parse(ScheduleTimeFormat, sc.EndAt)
+		if err != nil {
+			return fmt.Errorf("Schedule end '%s' is not in format %s", sc.EndAt, ScheduleTimeFormat)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("Schedule end %s is not after start %s", sc.EndAt, sc.StartAt)
+		}
+	} else if sc.DeleteAtEnd {
+		return errors.New("Schedule without an end cannot be deleted at end")
+	}
+
+	for _, day := range sc.RecurringDays {
+		if !stringInSlice(day, scheduleDays) {
+			return fmt.Errorf("Unknown recurring day '%s', expected one of %s", day, scheduleDays)
+		}
+	}
+
+	if len(sc.Actions) == 0 {
+		return errors.New("Schedule has no actions")
+	}
+	for _, action := range sc.Actions {
+		if !stringInSlice(action.Type, scheduleActionTypes) {
+			return fmt.Errorf("Unknown schedule action '%s', expected one of %s", action.Type, scheduleActionTypes)
+		}
+		if action.Offset < 0 || action.Offset >= 24*3600 {
+			return fmt.Errorf("Schedule action offset %d is outside of a day", action.Offset)
+		}
+		if action.Params != nil && len(action.Params.VmIds) > 0 && sc.TemplateId != "" {
+			return errors.New("Per-VM schedule actions are only supported for environments")
+		}
+	}
+	return nil
+}
+
+func scheduleIdPath(scheduleId string) string {
+	return fmt.Sprintf("%s/%s.json", SchedulePath, scheduleId)
+}
+
+/*
+ Create a schedule for this environment. The given schedule is not modified.
+*/
+func (e *Environment) CreateSchedule(client SkytapClient, schedule *Schedule) (*Schedule, error) {
+	body := *schedule
+	body.EnvironmentId = e.Id
+	return CreateSchedule(client, &body)
+}
+
+/*
+ List the schedules of this environment.
+*/
+func (e *Environment) ListSchedules(client SkytapClient) ([]Schedule, error) {
+	return listSchedules(client, func(sc *Schedule) bool { return sc.EnvironmentId == e.Id })
+}
+
+/*
+ Create a schedule for this template. The given schedule is not modified.
+*/
+func (t *Template) CreateSchedule(client SkytapClient, schedule *Schedule) (*Schedule, error) {
+	body := *schedule
+	body.TemplateId = t.Id
+	return CreateSchedule(client, &body)
+}
+
+/*
+ List the schedules of this template.
+*/
+func (t *Template) ListSchedules(client SkytapClient) ([]Schedule, error) {
+	return listSchedules(client, func(sc *Schedule) bool { return sc.TemplateId == t.Id })
+}
+
+/*
+ Create a schedule. The schedule is validated before the request is sent.
+*/
+func CreateSchedule(client SkytapClient, schedule *Schedule) (*Schedule, error) {
+	log.WithFields(log.Fields{"envId": schedule.EnvironmentId, "templateId": schedule.TemplateId, "title": schedule.Title}).Info("Creating schedule")
+
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(SchedulePath + ".json").BodyJSON(schedule)
+	}
+
+	created := &Schedule{}
+	_, err := RunSkytapRequest(client, false, created, createReq)
+	return created, err
+}
+
+/*
+ Return all schedules visible to the user.
+*/
+func ListSchedules(client SkytapClient) ([]Schedule, error) {
+	return listSchedules(client, func(*Schedule) bool { return true })
+}
+
+/*
+ Return a schedule by id.
+*/
+func GetSchedule(client SkytapClient, scheduleId string) (*Schedule, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(scheduleIdPath(scheduleId))
+	}
+
+	schedule := &Schedule{}
+	_, err := RunSkytapRequest(client, false, schedule, getReq)
+	return schedule, err
+}
+
+/*
+ Replace an existing schedule, identified by its id. The schedule is validated before the request is sent.
+*/
+func UpdateSchedule(client SkytapClient, schedule *Schedule) (*Schedule, error) {
+	log.WithFields(log.Fields{"scheduleId": schedule.Id}).Info("Updating schedule")
+
+	if schedule.Id == "" {
+		return nil, errors.New("Schedule id is required for updates")
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(scheduleIdPath(schedule.Id)).BodyJSON(schedule)
+	}
+
+	updated := &Schedule{}
+	_, err := RunSkytapRequest(client, false, updated, updateReq)
+	return updated, err
+}
+
+/*
+ Delete a schedule by id.
+*/
+func DeleteSchedule(client SkytapClient, scheduleId string) error {
+	log.WithFields(log.Fields{"scheduleId": scheduleId}).Info("Deleting schedule")
+
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(scheduleIdPath(scheduleId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
+
+func listSchedules(client SkytapClient, include func(*Schedule) bool) ([]Schedule, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(SchedulePath + ".json")
+	}
+
+	all := []Schedule{}
+	_, err := RunSkytapRequest(client, false, &all, listReq)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := []Schedule{}
+	for i := range all {
+		if include(&all[i]) {
+			schedules = append(schedules, all[i])
+		}
+	}
+	return schedules, nil
+}
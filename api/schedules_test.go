@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func weekdaySchedule() *Schedule {
+	schedule := &Schedule{
+		Title:         "Office hours",
+		TimeZone:      "Pacific Time (US & Canada)",
+		RecurringDays: []string{ScheduleMonday, ScheduleTuesday, ScheduleWednesday, ScheduleThursday, ScheduleFriday},
+		Actions: []ScheduleAction{
+			NewScheduleAction(ScheduleActionStart, 8, 0),
+			NewScheduleAction(ScheduleActionSuspend, 19, 0, "1001"),
+		},
+	}
+	schedule.SetStart(time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC))
+	return schedule
+}
+
+func TestCreateSchedule(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	env := &Environment{Id: "1"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/schedules.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"title":"Office hours","configuration_id":"1","time_zone":"Pacific Time (US \u0026 Canada)","start_at":"2030/01/07 00:00:00",`+
+			`"recurring_days":["monday","tuesday","wednesday","thursday","friday"],`+
+			`"actions":[{"type":"run","offset":28800},{"type":"suspend","offset":68400,"params":{"vm_ids":["1001"]}}]}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, strings.Replace(string(body), `{"title"`, `{"id":"7","title"`, 1))
+	})
+
+	request := weekdaySchedule()
+	schedule, err := env.CreateSchedule(client, request)
+	require.NoError(t, err, "Error creating schedule")
+	require.Equal(t, "7", schedule.Id)
+	require.Equal(t, "1", schedule.EnvironmentId)
+	require.Equal(t, "", request.EnvironmentId, "Should not change the request")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/schedules.json", r.URL.Path)
+		fmt.Fprintln(w, `[{"id":"7","configuration_id":"1"},{"id":"8","configuration_id":"2"},{"id":"9","template_id":"1"}]`)
+	})
+
+	schedules, err := env.ListSchedules(client)
+	require.NoError(t, err, "Error listing schedules")
+	require.Len(t, schedules, 1)
+	require.Equal(t, "7", schedules[0].Id)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/schedules/7.json", r.URL.Path)
+	})
+
+	err = DeleteSchedule(client, "7")
+	require.NoError(t, err, "Error deleting schedule")
+}
+
+func TestScheduleValidation(t *testing.T) {
+	schedule := weekdaySchedule()
+	require.Error(t, schedule.Validate(), "Should require an environment or template")
+
+	schedule.EnvironmentId = "1"
+	require.NoError(t, schedule.Validate())
+
+	schedule.TemplateId = "2"
+	require.Error(t, schedule.Validate(), "Should not allow both environment and template")
+	schedule.EnvironmentId = ""
+	require.Error(t, schedule.Validate(), "Should not allow per-VM actions on templates")
+	schedule.TemplateId = ""
+	schedule.EnvironmentId = "1"
+
+	schedule.SetEnd(time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.Error(t, schedule.Validate(), "Should require end after start")
+	schedule.EndAt = ""
+
+	schedule.RecurringDays = []string{"mon"}
+	require.Error(t, schedule.Validate(), "Should reject unknown days")
+	schedule.RecurringDays = nil
+
+	schedule.Actions = []ScheduleAction{NewScheduleAction("reboot", 8, 0)}
+	require.Error(t, schedule.Validate(), "Should reject unknown actions")
+	schedule.Actions = []ScheduleAction{NewScheduleAction(ScheduleActionStop, 24, 0)}
+	require.Error(t, schedule.Validate(), "Should reject offsets beyond a day")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Invalid schedule should not be sent")
+	})
+
+	_, err := CreateSchedule(client, schedule)
+	require.Error(t, err)
+}
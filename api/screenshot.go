@@ -0,0 +1,96 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ScreenshotPath = "screenshot"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func screenshotPath(vmId string) string {
+	return fmt.Sprintf("%s/%s/%s.png", VmPath, vmId, ScreenshotPath)
+}
+
+/*
+ Capture the current console of the VM as a PNG image. The VM must be running.
+*/
+func (vm *VirtualMachine) Screenshot(ctx context.Context, client SkytapClient) ([]byte, error) {
+	req, err := sling.New().Get(apiBaseUrl(false) + "/" + screenshotPath(vm.Id)).Request()
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	setRequestHeaders(client, req, false)
+	req.Header.Set("Accept", "image/png")
+
+	resp, err := doSkytapRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if !isOkStatus(resp.StatusCode) {
+		return nil, fmt.Errorf("Unable to capture screenshot of VM %s: %s", vm.Id, resp.Status)
+	}
+
+	image, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(image, pngSignature) {
+		return nil, fmt.Errorf("Screenshot of VM %s is not a PNG image", vm.Id)
+	}
+	return image, nil
+}
+
+/*
+ Return a SkytapClient.OnWaitTimeout hook that saves a screenshot of VMs that didn't reach the desired runstate into
+ dir, as vm-<id>-<time>.png. Other resources are ignored.
+*/
+func ScreenshotOnTimeout(dir string) func(client SkytapClient, r RunstateAwareResource, err error) {
+	return func(client SkytapClient, r RunstateAwareResource, waitErr error) {
+		vm, ok := r.(*VirtualMachine)
+		if !ok || vm == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		image, err := vm.Screenshot(ctx, client)
+		if err != nil {
+			log.WithFields(log.Fields{"vmId": vm.Id, "error": err}).Warn("Unable to capture screenshot after wait timeout")
+			return
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("vm-%s-%s.png", vm.Id, time.Now().UTC().Format("20060102-150405")))
+		if err = ioutil.WriteFile(path, image, 0644); err != nil {
+			log.WithFields(log.Fields{"vmId": vm.Id, "path": path, "error": err}).Warn("Unable to save screenshot")
+			return
+		}
+		log.WithFields(log.Fields{"vmId": vm.Id, "path": path, "waitError": waitErr}).Info("Saved screenshot after wait timeout")
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreenshotOnTimeout(t *testing.T) {
+	vmJson := readJson(t, "testdata/vm-1001.json")
+	png := "\x89PNG\r\n\x1a\nimage"
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	busy := true
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vms/1001":
+			fmt.Fprintln(w, strings.Replace(vmJson, `"runstate": "stopped"`, `"runstate": "busy"`, 1))
+		case "/vms/1001/screenshot.png":
+			require.Equal(t, "image/png", r.Header.Get("Accept"))
+			if busy {
+				busy = false
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(423)
+				return
+			}
+			fmt.Fprint(w, png)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	})
+
+	// Without an HTTP client the default one is used, busy responses are retried
+	client.HttpClient = nil
+	vm := &VirtualMachine{Id: "1001"}
+	image, err := vm.Screenshot(context.Background(), client)
+	require.NoError(t, err, "Error capturing screenshot")
+	require.Equal(t, png, string(image))
+	require.False(t, busy)
+
+	dir, err := ioutil.TempDir("", "screenshots")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(period time.Duration, periods int) {
+		waitPeriod, maxBusyWaitPeriods = period, periods
+	}(waitPeriod, maxBusyWaitPeriods)
+	waitPeriod, maxBusyWaitPeriods = time.Millisecond, 1
+	client.OnWaitTimeout = ScreenshotOnTimeout(dir)
+
+	_, err = vm.WaitUntilReady(client)
+	require.Error(t, err, "Should time out waiting for a busy VM")
+	files, _ := filepath.Glob(filepath.Join(dir, "vm-1001-*.png"))
+	require.Len(t, files, 1)
+}
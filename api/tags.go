@@ -0,0 +1,163 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	TagPath = "tags"
+)
+
+/*
+ Free-form tag on an environment or template. Tags are separate from labels.
+*/
+type Tag struct {
+	Id    string `json:"id,omitempty"`
+	Value string `json:"value"`
+}
+
+// Paths for tags, relative to the owning resource (e.g. "configurations/1").
+func tagsPath(ownerPath string) string { return fmt.Sprintf("%s/%s.json", ownerPath, TagPath) }
+func tagIdPath(ownerPath string, tagId string) string {
+	return fmt.Sprintf("%s/%s/%s.json", ownerPath, TagPath, tagId)
+}
+
+/*
+ Return the tags of an environment.
+*/
+func (e *Environment) GetTags(client SkytapClient) ([]Tag, error) {
+	return getTags(client, environmentIdV1Path(e.Id))
+}
+
+/*
+ Add one or more tags to an environment. Returns the complete list of tags after the change.
+*/
+func (e *Environment) AddTags(client SkytapClient, values ...string) ([]Tag, error) {
+	log.WithFields(log.Fields{"envId": e.Id, "tags": values}).Info("Adding tags to environment")
+
+	tags, err := addTags(client, environmentIdV1Path(e.Id), values)
+	if err == nil {
+		e.Tags = tags
+	}
+	return tags, err
+}
+
+/*
+ Remove a tag, by tag id, from an environment. The tag is also removed from Tags.
+*/
+func (e *Environment) RemoveTag(client SkytapClient, tagId string) error {
+	log.WithFields(log.Fields{"envId": e.Id, "tagId": tagId}).Info("Removing tag from environment")
+
+	err := removeTag(client, environmentIdV1Path(e.Id), tagId)
+	if err == nil {
+		e.Tags = withoutTag(e.Tags, tagId)
+	}
+	return err
+}
+
+/*
+ Return the tags of a template.
+*/
+func (t *Template) GetTags(client SkytapClient) ([]Tag, error) {
+	return getTags(client, templateIdV1Path(t.Id))
+}
+
+/*
+ Add one or more tags to a template. Returns the complete list of tags after the change.
+*/
+func (t *Template) AddTags(client SkytapClient, values ...string) ([]Tag, error) {
+	log.WithFields(log.Fields{"templateId": t.Id, "tags": values}).Info("Adding tags to template")
+
+	tags, err := addTags(client, templateIdV1Path(t.Id), values)
+	if err == nil {
+		t.Tags = tags
+	}
+	return tags, err
+}
+
+/*
+ Remove a tag, by tag id, from a template. The tag is also removed from Tags.
+*/
+func (t *Template) RemoveTag(client SkytapClient, tagId string) error {
+	log.WithFields(log.Fields{"templateId": t.Id, "tagId": tagId}).Info("Removing tag from template")
+
+	err := removeTag(client, templateIdV1Path(t.Id), tagId)
+	if err == nil {
+		t.Tags = withoutTag(t.Tags, tagId)
+	}
+	return err
+}
+
+/*
+ Return the tag with the given value, or nil if not present.
+*/
+func FindTag(tags []Tag, value string) *Tag {
+	for i := range tags {
+		if tags[i].Value == value {
+			return &tags[i]
+		}
+	}
+	return nil
+}
+
+// Copy of tags without the tag with the given id.
+func withoutTag(tags []Tag, tagId string) []Tag {
+	remaining := []Tag{}
+	for _, tag := range tags {
+		if tag.Id != tagId {
+			remaining = append(remaining, tag)
+		}
+	}
+	return remaining
+}
+
+func getTags(client SkytapClient, ownerPath string) ([]Tag, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(tagsPath(ownerPath))
+	}
+
+	tags := []Tag{}
+	_, err := RunSkytapRequest(client, true, &tags, getReq)
+	return tags, err
+}
+
+func addTags(client SkytapClient, ownerPath string, values []string) ([]Tag, error) {
+	body := make([]Tag, len(values))
+	for i, v := range values {
+		body[i] = Tag{Value: v}
+	}
+
+	addReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(tagsPath(ownerPath)).BodyJSON(body)
+	}
+
+	tags := []Tag{}
+	_, err := RunSkytapRequest(client, true, &tags, addReq)
+	return tags, err
+}
+
+func removeTag(client SkytapClient, ownerPath string, tagId string) error {
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(tagIdPath(ownerPath, tagId))
+	}
+
+	_, err := RunSkytapRequest(client, true, nil, delReq)
+	return err
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentTags(t *testing.T) {
+	envJson := readJson(t, "testdata/environment-1.json")
+
+	client := skytapClient(t)
+	server := getMockServerForString(client, envJson)
+	defer server.Close()
+
+	env, err := GetEnvironment(client, "1")
+	require.NoError(t, err, "Error getting environment")
+	require.Equal(t, []Tag{{Id: "1", Value: "keep-alive"}}, env.Tags)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/configurations/1/tags.json", r.URL.Path)
+		fmt.Fprintln(w, `[{"id":"1","value":"keep-alive"}]`)
+	})
+
+	tags, err := env.GetTags(client)
+	require.NoError(t, err, "Error getting tags")
+	require.Equal(t, "keep-alive", tags[0].Value)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/configurations/1/tags.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `[{"value":"ephemeral"}]`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `[{"id":"1","value":"keep-alive"},{"id":"2","value":"ephemeral"}]`)
+	})
+
+	tags, err = env.AddTags(client, "ephemeral")
+	require.NoError(t, err, "Error adding tags")
+	require.Len(t, tags, 2)
+	require.Equal(t, "2", FindTag(env.Tags, "ephemeral").Id)
+	require.Nil(t, FindTag(env.Tags, "missing"))
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/configurations/1/tags/2.json", r.URL.Path)
+	})
+
+	err = env.RemoveTag(client, "2")
+	require.NoError(t, err, "Error removing tag")
+	require.Nil(t, FindTag(env.Tags, "ephemeral"))
+	require.Len(t, env.Tags, 1)
+}
+
+func TestTemplateTags(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	template := &Template{Id: "2"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/templates/2/tags.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `[{"value":"a"},{"value":"b"}]`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `[{"id":"3","value":"a"},{"id":"4","value":"b"}]`)
+	})
+
+	tags, err := template.AddTags(client, "a", "b")
+	require.NoError(t, err, "Error adding tags")
+	require.Equal(t, tags, template.Tags)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/templates/2/tags/3.json", r.URL.Path)
+	})
+
+	err = template.RemoveTag(client, "3")
+	require.NoError(t, err, "Error removing tag")
+	require.Equal(t, []Tag{{Id: "4", Value: "b"}}, template.Tags)
+}
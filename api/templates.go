@@ -26,4 +26,7 @@ type Template struct {
 	Url    string `json:"url"`
 	Name   string `json:"name"`
 	Region string `json:"region"`
+	Tags   []Tag  `json:"tags,omitempty"`
 }
+
+func templateIdV1Path(templateId string) string { return TemplatePath + "/" + templateId }
@@ -0,0 +1,114 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	TunnelPath = "tunnels"
+)
+
+/*
+ Inter-configuration network routing (ICNR) tunnel between networks of two environments.
+*/
+type Tunnel struct {
+	Id            string  `json:"id"`
+	Status        string  `json:"status"`
+	Error         string  `json:"error"`
+	SourceNetwork Network `json:"source_network"`
+	TargetNetwork Network `json:"target_network"`
+}
+
+/*
+ Request body for tunnel create commands.
+*/
+type CreateTunnelBody struct {
+	SourceNetworkId string `json:"source_network_id"`
+	TargetNetworkId string `json:"target_network_id"`
+}
+
+func tunnelIdPath(tunnelId string) string { return fmt.Sprintf("%s/%s.json", TunnelPath, tunnelId) }
+
+/*
+ Check that two networks can be connected by a tunnel: both must be tunnelable, and their subnets must not overlap.
+*/
+func ValidateTunnel(source *Network, target *Network) error {
+	if !source.Tunnelable {
+		return fmt.Errorf("Network %s (%s) is not tunnelable", source.Id, source.Name)
+	}
+	if !target.Tunnelable {
+		return fmt.Errorf("Network %s (%s) is not tunnelable", target.Id, target.Name)
+	}
+	overlap, err := subnetsOverlap(source.Subnet, target.Subnet)
+	if err != nil {
+		return err
+	}
+	if overlap {
+		return fmt.Errorf("Subnet %s of network %s overlaps subnet %s of network %s", source.Subnet, source.Id, target.Subnet, target.Id)
+	}
+	return nil
+}
+
+/*
+ Create a tunnel from the source network to the target network, after validating them with ValidateTunnel.
+*/
+func CreateTunnel(client SkytapClient, source *Network, target *Network) (*Tunnel, error) {
+	log.WithFields(log.Fields{"sourceNetId": source.Id, "targetNetId": target.Id}).Info("Creating tunnel")
+
+	if err := ValidateTunnel(source, target); err != nil {
+		return nil, err
+	}
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(TunnelPath + ".json").BodyJSON(&CreateTunnelBody{SourceNetworkId: source.Id, TargetNetworkId: target.Id})
+	}
+
+	tunnel := &Tunnel{}
+	_, err := RunSkytapRequest(client, false, tunnel, createReq)
+	return tunnel, err
+}
+
+/*
+ List the tunnels of a network, in either direction.
+*/
+func ListTunnels(client SkytapClient, envId string, netId string) ([]Tunnel, error) {
+	network, err := GetNetwork(client, envId, netId)
+	if err != nil {
+		return nil, err
+	}
+	if network.Tunnels == nil {
+		return []Tunnel{}, nil
+	}
+	return network.Tunnels, nil
+}
+
+/*
+ Remove a tunnel.
+*/
+func DeleteTunnel(client SkytapClient, tunnelId string) error {
+	log.WithFields(log.Fields{"tunnelId": tunnelId}).Info("Deleting tunnel")
+
+	deleteReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(tunnelIdPath(tunnelId))
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, deleteReq)
+	return err
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTunnel(t *testing.T) {
+	netJson := readJson(t, "testdata/network-1.json")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/configurations/1/networks/99.json", r.URL.Path)
+		fmt.Fprintln(w, netJson)
+	})
+	tunnels, err := ListTunnels(client, "1", "99")
+	require.NoError(t, err, "Error listing tunnels")
+	require.Len(t, tunnels, 1)
+	require.Equal(t, "tunnel-6631420-11110493", tunnels[0].Id)
+	require.Equal(t, "98", tunnels[0].TargetNetwork.Id)
+
+	source := &Network{Id: "99", Subnet: "10.0.0.0/24", Tunnelable: true}
+	target := &Network{Id: "98", Subnet: "10.0.0.128/25", Tunnelable: true}
+	_, err = CreateTunnel(client, source, target)
+	require.Error(t, err, "Should not tunnel overlapping subnets")
+
+	target.Subnet = "10.0.1.0/24"
+	target.Tunnelable = false
+	_, err = CreateTunnel(client, source, target)
+	require.Error(t, err, "Should not tunnel to a network that isn't tunnelable")
+
+	target.Tunnelable = true
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/tunnels.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"source_network_id":"99","target_network_id":"98"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"tunnel-99-98","status":"busy"}`)
+	})
+	tunnel, err := CreateTunnel(client, source, target)
+	require.NoError(t, err, "Error creating tunnel")
+	require.Equal(t, "tunnel-99-98", tunnel.Id)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/tunnels/tunnel-99-98.json", r.URL.Path)
+	})
+	require.NoError(t, DeleteTunnel(client, tunnel.Id), "Error deleting tunnel")
+}
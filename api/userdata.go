@@ -0,0 +1,123 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	UserDataPath = "user_data"
+)
+
+/*
+ User data attached to an environment or VM. Guests can read it from the metadata service (see MetadataUri).
+*/
+type UserData struct {
+	Contents string `json:"contents"`
+}
+
+func userDataPath(ownerPath string) string { return fmt.Sprintf("%s/%s.json", ownerPath, UserDataPath) }
+
+/*
+ Return the user data of an environment.
+*/
+func (e *Environment) GetUserData(client SkytapClient) (*UserData, error) {
+	return getUserData(client, environmentIdV1Path(e.Id))
+}
+
+/*
+ Replace the user data of an environment.
+*/
+func (e *Environment) SetUserData(client SkytapClient, contents string) (*UserData, error) {
+	log.WithFields(log.Fields{"envId": e.Id}).Info("Setting environment user data")
+
+	return setUserData(client, environmentIdV1Path(e.Id), contents)
+}
+
+/*
+ Return the user data of a VM.
+*/
+func (vm *VirtualMachine) GetUserData(client SkytapClient) (*UserData, error) {
+	return getUserData(client, vmIdPath(vm.Id))
+}
+
+/*
+ Replace the user data of a VM.
+*/
+func (vm *VirtualMachine) SetUserData(client SkytapClient, contents string) (*UserData, error) {
+	log.WithFields(log.Fields{"vmId": vm.Id}).Info("Setting VM user data")
+
+	return setUserData(client, vmIdPath(vm.Id), contents)
+}
+
+/*
+ Decode JSON formatted user data contents into v.
+*/
+func (u *UserData) DecodeJSON(v interface{}) error {
+	return DecodeUserDataJSON(u.Contents, v)
+}
+
+/*
+ Decode YAML formatted user data contents into v.
+*/
+func (u *UserData) DecodeYAML(v interface{}) error {
+	return DecodeUserDataYAML(u.Contents, v)
+}
+
+/*
+ Decode JSON formatted user data into v. Useful for contents read from the metadata service.
+*/
+func DecodeUserDataJSON(contents string, v interface{}) error {
+	if err := json.Unmarshal([]byte(contents), v); err != nil {
+		return fmt.Errorf("Unable to decode user data as JSON: %s", err)
+	}
+	return nil
+}
+
+/*
+ Decode YAML formatted user data into v. Useful for contents read from the metadata service.
+*/
+func DecodeUserDataYAML(contents string, v interface{}) error {
+	if err := yaml.Unmarshal([]byte(contents), v); err != nil {
+		return fmt.Errorf("Unable to decode user data as YAML: %s", err)
+	}
+	return nil
+}
+
+func getUserData(client SkytapClient, ownerPath string) (*UserData, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(userDataPath(ownerPath))
+	}
+
+	userData := &UserData{}
+	_, err := RunSkytapRequest(client, false, userData, getReq)
+	return userData, err
+}
+
+func setUserData(client SkytapClient, ownerPath string, contents string) (*UserData, error) {
+	setReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(userDataPath(ownerPath)).BodyJSON(&UserData{Contents: contents})
+	}
+
+	userData := &UserData{}
+	_, err := RunSkytapRequest(client, false, userData, setReq)
+	return userData, err
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentUserData(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	env := &Environment{Id: "1"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/configurations/1/user_data.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"contents":"role: web\nreplicas: 2\n"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"contents":"role: web\nreplicas: 2\n"}`)
+	})
+
+	userData, err := env.SetUserData(client, "role: web\nreplicas: 2\n")
+	require.NoError(t, err, "Error setting user data")
+
+	config := struct {
+		Role     string `yaml:"role"`
+		Replicas int    `yaml:"replicas"`
+	}{}
+	require.NoError(t, userData.DecodeYAML(&config), "Error decoding user data")
+	require.Equal(t, "web", config.Role)
+	require.Equal(t, 2, config.Replicas)
+}
+
+func TestVmUserData(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	vm := &VirtualMachine{Id: "1001"}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/vms/1001/user_data.json", r.URL.Path)
+		fmt.Fprintln(w, `{"contents":"{\"role\":\"db\"}"}`)
+	})
+
+	userData, err := vm.GetUserData(client)
+	require.NoError(t, err, "Error getting user data")
+
+	config := map[string]string{}
+	require.NoError(t, userData.DecodeJSON(&config), "Error decoding user data")
+	require.Equal(t, "db", config["role"])
+
+	require.Error(t, DecodeUserDataJSON("role: db", &config))
+}
@@ -0,0 +1,144 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	UserPath = "users"
+
+	AccountRoleRestricted = "restricted_user"
+	AccountRoleStandard   = "standard_user"
+	AccountRoleAdmin      = "admin"
+)
+
+/*
+ Skytap user resource.
+
+ Also used as request body for create commands.
+*/
+type User struct {
+	Id          string `json:"id,omitempty"`
+	Url         string `json:"url,omitempty"`
+	FirstName   string `json:"first_name,omitempty"`
+	LastName    string `json:"last_name,omitempty"`
+	LoginName   string `json:"login_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Title       string `json:"title,omitempty"`
+	TimeZone    string `json:"time_zone,omitempty"`
+	AccountRole string `json:"account_role,omitempty"`
+	CanImport   *bool  `json:"can_import,omitempty"`
+	CanExport   *bool  `json:"can_export,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+}
+
+/*
+ Request body for user update commands, nil fields are left unchanged and empty strings clear a field.
+*/
+type UserUpdate struct {
+	FirstName   *string `json:"first_name,omitempty"`
+	LastName    *string `json:"last_name,omitempty"`
+	LoginName   *string `json:"login_name,omitempty"`
+	Email       *string `json:"email,omitempty"`
+	Title       *string `json:"title,omitempty"`
+	TimeZone    *string `json:"time_zone,omitempty"`
+	AccountRole *string `json:"account_role,omitempty"`
+	CanImport   *bool   `json:"can_import,omitempty"`
+	CanExport   *bool   `json:"can_export,omitempty"`
+}
+
+/*
+ Query for user delete commands.
+*/
+type DeleteUserQuery struct {
+	TransferUserId string `url:"transfer_user_id,omitempty"`
+}
+
+func userIdPath(userId string) string { return fmt.Sprintf("%s/%s.json", UserPath, userId) }
+
+/*
+ List all users of the company.
+*/
+func ListUsers(client SkytapClient) ([]User, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(UserPath + ".json")
+	}
+
+	users := []User{}
+	_, err := RunSkytapRequest(client, false, &users, listReq)
+	return users, err
+}
+
+/*
+ Return an existing user by id.
+*/
+func GetUser(client SkytapClient, userId string) (*User, error) {
+	getReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(userIdPath(userId))
+	}
+
+	user := &User{}
+	_, err := RunSkytapRequest(client, false, user, getReq)
+	return user, err
+}
+
+/*
+ Create a new user. LoginName and Email are required.
+*/
+func CreateUser(client SkytapClient, user *User) (*User, error) {
+	log.WithFields(log.Fields{"loginName": user.LoginName}).Info("Creating user")
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(UserPath + ".json").BodyJSON(user)
+	}
+
+	created := &User{}
+	_, err := RunSkytapRequest(client, false, created, createReq)
+	return created, err
+}
+
+/*
+ Update a user. Only the fields set in changes are sent.
+*/
+func UpdateUser(client SkytapClient, userId string, changes *UserUpdate) (*User, error) {
+	log.WithFields(log.Fields{"userId": userId}).Info("Updating user")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(userIdPath(userId)).BodyJSON(changes)
+	}
+
+	updated := &User{}
+	_, err := RunSkytapRequest(client, false, updated, updateReq)
+	return updated, err
+}
+
+/*
+ Delete a user. The user's environments, templates and assets are transferred to transferUserId.
+*/
+func DeleteUser(client SkytapClient, userId string, transferUserId string) error {
+	log.WithFields(log.Fields{"userId": userId, "transferUserId": transferUserId}).Info("Deleting user")
+
+	delReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(userIdPath(userId)).QueryStruct(&DeleteUserQuery{TransferUserId: transferUserId})
+	}
+
+	_, err := RunSkytapRequest(client, false, nil, delReq)
+	return err
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUser(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/users.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"first_name":"Jane","last_name":"Doe","login_name":"jane.doe@example.com","email":"jane.doe@example.com","account_role":"standard_user"}`,
+			strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"15386","first_name":"Jane","last_name":"Doe","login_name":"jane.doe@example.com","email":"jane.doe@example.com","account_role":"standard_user"}`)
+	})
+
+	user, err := CreateUser(client, &User{
+		FirstName:   "Jane",
+		LastName:    "Doe",
+		LoginName:   "jane.doe@example.com",
+		Email:       "jane.doe@example.com",
+		AccountRole: AccountRoleStandard,
+	})
+	require.NoError(t, err, "Error creating user")
+	require.Equal(t, "15386", user.Id)
+}
+
+func TestUpdateUser(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/users/15386.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"title":"","account_role":"admin"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"15386","account_role":"admin"}`)
+	})
+
+	title, role := "", AccountRoleAdmin
+	user, err := UpdateUser(client, "15386", &UserUpdate{Title: &title, AccountRole: &role})
+	require.NoError(t, err, "Error updating user")
+	require.Equal(t, AccountRoleAdmin, user.AccountRole)
+}
+
+func TestDeleteUser(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/users/15386.json", r.URL.Path)
+		require.Equal(t, "transfer_user_id=1", r.URL.RawQuery)
+	})
+
+	err := DeleteUser(client, "15386", "1")
+	require.NoError(t, err, "Error deleting user")
+}
+
+func TestGroupMembership(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/groups/3.json", r.URL.Path)
+		fmt.Fprintln(w, `{"id":"3","name":"QA","users":[{"id":"15386","login_name":"jane.doe@example.com"}]}`)
+	})
+
+	group, err := GetGroup(client, "3")
+	require.NoError(t, err, "Error getting group")
+	require.Equal(t, "jane.doe@example.com", group.Users[0].LoginName)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/groups/3.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"description":""}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"3","name":"QA"}`)
+	})
+
+	description := ""
+	_, err = UpdateGroup(client, "3", &GroupUpdate{Description: &description})
+	require.NoError(t, err, "Error updating group")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/groups/3/users/42.json", r.URL.Path)
+	})
+
+	err = group.AddUser(client, "42")
+	require.NoError(t, err, "Error adding user to group")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/groups/3/users/42.json", r.URL.Path)
+	})
+
+	err = group.RemoveUser(client, "42")
+	require.NoError(t, err, "Error removing user from group")
+}
+
+func TestDepartmentMembership(t *testing.T) {
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/departments.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"name":"Engineering"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"8","name":"Engineering"}`)
+	})
+
+	department, err := CreateDepartment(client, "Engineering", "")
+	require.NoError(t, err, "Error creating department")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/departments/8/users/42.json", r.URL.Path)
+	})
+
+	err = department.AddUser(client, "42")
+	require.NoError(t, err, "Error adding user to department")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/departments/8/users.json", r.URL.Path)
+		fmt.Fprintln(w, `[{"id":"42"}]`)
+	})
+
+	users, err := department.ListUsers(client)
+	require.NoError(t, err, "Error listing department users")
+	require.Equal(t, "42", users[0].Id)
+}
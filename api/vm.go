@@ -16,7 +16,6 @@ package api
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/dghubble/sling"
 	log "github.com/sirupsen/logrus"
@@ -24,6 +23,9 @@ import (
 
 const (
 	VmPath = "vms"
+
+	// Format of Hardware.RtcStartTime, see SetRtcStartTime.
+	RtcTimeFormat = "2006/01/02 15:04:05"
 )
 
 /*
@@ -39,6 +41,9 @@ type VirtualMachine struct {
 	Interfaces     []*NetworkInterface `json:"interfaces,omitempty"`
 	Hardware       Hardware            `json:"hardware,omitempty"`
 	CreatedAt      string              `json:"created_at,omitempty"`
+
+	HardwareVersion    int `json:"hardware_version,omitempty"`
+	MaxHardwareVersion int `json:"max_hardware_version,omitempty"`
 }
 
 type VmCredential struct {
@@ -50,25 +55,58 @@ type NameUpdate struct {
 	Hostname string `json:"hostname"`
 }
 
+/*
+ VM hardware. RAM and storage are in MB.
+
+ All fields are optional, so the struct can also be used as an update body. Limits, usage and the guest OS are reported
+ by Skytap and can't be changed, they are left out of update requests.
+*/
 type Hardware struct {
-	Cpus          *int   `json:"cpus,omitempty"`
-	CpusPerSocket *int   `json:"cpus_per_socket,omitempty"`
-	Ram           *int   `json:"ram,omitempty"`
-	Disks         []Disk `json:"disks,omitempty"`
+	Cpus                 *int    `json:"cpus,omitempty"`
+	CpusPerSocket        *int    `json:"cpus_per_socket,omitempty"`
+	Ram                  *int    `json:"ram,omitempty"`
+	Disks                []Disk  `json:"disks,omitempty"`
+	MaxCpus              *int    `json:"max_cpus,omitempty"`
+	MinRam               *int    `json:"min_ram,omitempty"`
+	MaxRam               *int    `json:"max_ram,omitempty"`
+	SupportsMulticore    *bool   `json:"supports_multicore,omitempty"`
+	Svms                 *int    `json:"svms,omitempty"`
+	Storage              *int    `json:"storage,omitempty"`
+	GuestOs              string  `json:"guestOS,omitempty"`
+	Architecture         string  `json:"architecture,omitempty"`
+	Upgradable           *bool   `json:"upgradable,omitempty"`
+	VncKeymap            *string `json:"vnc_keymap,omitempty"`
+	RtcStartTime         *string `json:"rtc_start_time,omitempty"`
+	TimeSyncEnabled      *bool   `json:"time_sync_enabled,omitempty"`
+	CopyPasteEnabled     *bool   `json:"copy_paste_enabled,omitempty"`
+	NestedVirtualization *bool   `json:"nested_virtualization,omitempty"`
 }
 
+/*
+ VM disk. Sizes are in MB.
+*/
 type Disk struct {
-	Id         string `json:"id"`
-	Size       *int   `json:"size"`
-	Type       string `json:"type"`
-	Controller string `json:"controller"`
-	Lun        string `json:"lun"`
+	Id          string `json:"id"`
+	Size        *int   `json:"size"`
+	Type        string `json:"type"`
+	Controller  string `json:"controller"`
+	Lun         string `json:"lun"`
+	Name        string `json:"name,omitempty"`
+	StorageTier string `json:"storage_tier,omitempty"`
 }
 
 type HardwareUpdate struct {
 	Hardware Hardware `json:"hardware"`
 }
 
+// Copy of the hardware without the fields reported by Skytap, so a copy of VirtualMachine.Hardware can be sent back.
+func (h Hardware) updateBody() *HardwareUpdate {
+	h.MaxCpus, h.MinRam, h.MaxRam, h.SupportsMulticore = nil, nil, nil, nil
+	h.Svms, h.Storage, h.Upgradable = nil, nil, nil
+	h.GuestOs, h.Architecture = "", ""
+	return &HardwareUpdate{Hardware: h}
+}
+
 // Paths for VMs.
 func vmIdInEnvironmentPath(envId string, vmId string) string {
 	return fmt.Sprintf("%s/%s/%s/%s.json", EnvironmentPath, envId, VmPath, vmId)
@@ -231,10 +269,11 @@ func (vm *VirtualMachine) GetCredentials(client SkytapClient) ([]VmCredential, e
 func (vm *VirtualMachine) AddDisk(client SkytapClient, envId string, diskSize int, restartVm bool) (*VirtualMachine, error) {
 
 	if vm.Runstate != RunStateStop {
-		vm, err := vm.Stop(client)
+		stopped, err := vm.Stop(client)
 		if err != nil {
-			return vm, err
+			return stopped, err
 		}
+		vm = stopped
 	}
 
 	hw := map[string]interface{}{
@@ -268,10 +307,11 @@ func (vm *VirtualMachine) AddDisk(client SkytapClient, envId string, diskSize in
 */
 func (vm *VirtualMachine) ResizeDisk(client SkytapClient, envId string, diskId string, diskSize int, restartVm bool) (*VirtualMachine, error) {
 	if vm.Runstate != RunStateStop {
-		vm, err := vm.Stop(client)
+		stopped, err := vm.Stop(client)
 		if err != nil {
-			return vm, err
+			return stopped, err
 		}
+		vm = stopped
 	}
 
 	hw := map[string]interface{}{
@@ -305,11 +345,58 @@ func (vm *VirtualMachine) ResizeDisk(client SkytapClient, envId string, diskId s
 
 }
 
+/*
+ Remove the disk with specified ID. The VM is stopped first if needed.
+*/
+func (vm *VirtualMachine) RemoveDisk(client SkytapClient, diskId string, restartVm bool) (*VirtualMachine, error) {
+	if findDisk(vm.Hardware.Disks, diskId) == nil {
+		return vm, fmt.Errorf("VM %s has no disk %s", vm.Id, diskId)
+	}
+
+	log.WithFields(log.Fields{"vmId": vm.Id, "diskId": diskId}).Infof("Removing disk")
+	// A size of zero deletes an existing disk
+	return vm.ResizeDisk(client, "", diskId, 0, restartVm)
+}
+
 /*
  Add a network interface to VM
 */
 func (vm *VirtualMachine) AddNetworkInterface(client SkytapClient, envId, ip, host, nic_type string, restartVm bool) (*NetworkInterface, error) {
-	log.WithFields(log.Fields{"envId": envId, "vmId": vm.Id, "nic_type": nic_type, "ip": ip, "hostname": host}).Infof("Adding interface")
+	intr := &NetworkInterface{
+		Ip:       ip,
+		Hostname: host,
+		NicType:  nic_type,
+	}
+	return vm.addNetworkInterface(client, envId, intr, restartVm)
+}
+
+/*
+ Add a network interface to VM, connected to the network of allocator, with its next free static IP.
+
+ The allocator is created by the caller, e.g. with NewIpAllocator, so reserved ranges are honored and repeated calls
+ for several VMs don't pick the same address. The address is released again if the interface can't be added.
+*/
+func (vm *VirtualMachine) AddNetworkInterfaceWithAutoIp(client SkytapClient, envId string, allocator *IpAllocator, host, nic_type string, restartVm bool) (*NetworkInterface, error) {
+	ip, err := allocator.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	intr := &NetworkInterface{
+		Ip:        ip,
+		Hostname:  host,
+		NicType:   nic_type,
+		NetworkId: allocator.NetworkId(),
+	}
+	added, err := vm.addNetworkInterface(client, envId, intr, restartVm)
+	if added == nil {
+		allocator.Release(ip)
+	}
+	return added, err
+}
+
+func (vm *VirtualMachine) addNetworkInterface(client SkytapClient, envId string, intr *NetworkInterface, restartVm bool) (*NetworkInterface, error) {
+	log.WithFields(log.Fields{"envId": envId, "vmId": vm.Id, "nic_type": intr.NicType, "ip": intr.Ip, "hostname": intr.Hostname}).Infof("Adding interface")
 	if vm.Runstate != RunStateStop {
 		_, err := vm.Stop(client)
 		if err != nil {
@@ -318,12 +405,6 @@ func (vm *VirtualMachine) AddNetworkInterface(client SkytapClient, envId, ip, ho
 		vm.WaitUntilInState(client, []string{RunStateStop}, false)
 	}
 
-	intr := &NetworkInterface{
-		Ip:       ip,
-		Hostname: host,
-		NicType:  nic_type,
-	}
-
 	addReq := func(s *sling.Sling) *sling.Sling {
 		path := fmt.Sprintf("%s/%s/%s/%s/%s.json", EnvironmentPath, envId, VmPath, vm.Id, InterfacePath)
 		return s.Post(path).BodyJSON(intr)
@@ -391,14 +472,15 @@ func (vm *VirtualMachine) RenameNetworkInterface(client SkytapClient, envId stri
 
 func (vm *VirtualMachine) UpdateHardware(client SkytapClient, hardware Hardware, restartVm bool) (*VirtualMachine, error) {
 	if vm.Runstate != RunStateStop {
-		vm, err := vm.Stop(client)
+		stopped, err := vm.Stop(client)
 		if err != nil {
-			return vm, err
+			return stopped, err
 		}
+		vm = stopped
 	}
 
 	hardwareReq := func(s *sling.Sling) *sling.Sling {
-		return s.Put(vmUpdatePath(vm.Id)).BodyJSON(&HardwareUpdate{Hardware: hardware})
+		return s.Put(vmUpdatePath(vm.Id)).BodyJSON(hardware.updateBody())
 	}
 
 	newVm := &VirtualMachine{}
@@ -445,20 +527,26 @@ func (vm *VirtualMachine) SetContainerHost(client SkytapClient) (*VirtualMachine
 	return vm.ChangeAttribute(client, &ContainerHostQuery{true})
 }
 
+/*
+ Return the username of the credential, see ParseCredential.
+*/
 func (c *VmCredential) Username() (string, error) {
-	parts := strings.Split(c.Text, "/")
-	if len(parts) != 2 {
-		return "", fmt.Errorf("Incorrect parts in credential string '%s'", c.Text)
+	credential, err := c.Parse()
+	if err != nil {
+		return "", err
 	}
-	return strings.TrimSpace(parts[0]), nil
+	return credential.Username, nil
 }
 
+/*
+ Return the password of the credential, see ParseCredential.
+*/
 func (c *VmCredential) Password() (string, error) {
-	parts := strings.Split(c.Text, "/")
-	if len(parts) != 2 {
-		return "", fmt.Errorf("Incorrect parts in credential string '%s'", c.Text)
+	credential, err := c.Parse()
+	if err != nil {
+		return "", err
 	}
-	return strings.TrimSpace(parts[1]), nil
+	return credential.Password, nil
 }
 
 /*
@@ -186,7 +186,7 @@ func TestUpdateHardware(t *testing.T) {
 
 	cpus := 4
 	persock := 2
-	hardware := Hardware{&cpus, &persock, nil, nil}
+	hardware := Hardware{Cpus: &cpus, CpusPerSocket: &persock}
 
 	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		require.Equal(t, "/vms/1001.json", r.URL.Path)
@@ -226,6 +226,21 @@ func TestUpdateHardware(t *testing.T) {
 	require.Equal(t, hardware.Cpus, updated.Hardware.Cpus)
 	require.Equal(t, hardware.CpusPerSocket, updated.Hardware.CpusPerSocket)
 	require.Equal(t, updateRam.Ram, updated.Hardware.Ram)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Contains(t, string(body), `"cpus":2`)
+		for _, field := range []string{"max_cpus", "min_ram", "max_ram", "supports_multicore", "svms", `"storage"`, "guestOS", "architecture", "upgradable"} {
+			require.NotContains(t, string(body), field, "Should not send reported hardware fields")
+		}
+		fmt.Fprintln(w, vmJson)
+	})
+
+	copied := vm.Hardware
+	cpus = 2
+	copied.Cpus = &cpus
+	_, err = vm.UpdateHardware(client, copied, false)
+	require.NoError(t, err, "Error updating copied hardware")
 }
 
 func TestChangeName(t *testing.T) {
@@ -294,3 +309,76 @@ func TestDeleteNetworkInterface(t *testing.T) {
 func TestAddDisk(t *testing.T) {
 
 }
+
+func TestRemoveDisk(t *testing.T) {
+	vmJson := readJson(t, "testdata/vm-1001.json")
+
+	client := skytapClient(t)
+	server := getMockServerForString(client, vmJson)
+	defer server.Close()
+
+	vm, err := GetVirtualMachine(client, "1001")
+	require.NoError(t, err, "Error getting vm")
+	vm.Runstate = RunStateStop
+
+	inventory := vm.DiskInventory()
+	require.Len(t, inventory.Disks, 1)
+	require.Equal(t, 20480, inventory.TotalSize)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/vms/1001.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"hardware":{"disks":{"existing":{"disk-5971736-13548234-scsi-0-0":{"id":"disk-5971736-13548234-scsi-0-0","size":0}}}}}`,
+			strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, vmJson)
+	})
+
+	_, err = vm.RemoveDisk(client, "disk-5971736-13548234-scsi-0-0", false)
+	require.NoError(t, err, "Error removing disk")
+
+	_, err = vm.RemoveDisk(client, "disk-missing", false)
+	require.Error(t, err, "Should reject unknown disk")
+
+	env := &Environment{Id: "1", Vms: []*VirtualMachine{vm, vm}}
+	require.Equal(t, 40960, env.DiskInventory().TotalSize)
+}
+
+func TestAddNetworkInterfaceWithAutoIp(t *testing.T) {
+	envJson := readJson(t, "testdata/environment-1.json")
+
+	client := skytapClient(t)
+	server := getMockServerForString(client, envJson)
+	defer server.Close()
+
+	env, err := GetEnvironment(client, "1")
+	require.NoError(t, err, "Error getting environment")
+	vm := env.Vms[0]
+	vm.Runstate = RunStateStop
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/configurations/1/vms/"+vm.Id+"/interfaces.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"ip":"10.0.0.2","hostname":"host-2","nic_type":"vmxnet3","network_id":"99"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, `{"id":"nic-2","ip":"10.0.0.2","hostname":"host-2","nic_type":"vmxnet3","network_id":"99"}`)
+	})
+
+	allocator, err := NewIpAllocator(env, &env.Networks[0])
+	require.NoError(t, err, "Error creating IP allocator")
+	nic, err := vm.AddNetworkInterfaceWithAutoIp(client, env.Id, allocator, "host-2", "vmxnet3", false)
+	require.NoError(t, err, "Error adding network interface")
+	require.Equal(t, "10.0.0.2", nic.Ip)
+	require.Equal(t, "nic-2", nic.Id)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintln(w, `{"error":"IP in use"}`)
+	})
+
+	_, err = vm.AddNetworkInterfaceWithAutoIp(client, env.Id, allocator, "host-3", "vmxnet3", false)
+	require.Error(t, err, "Should fail when the interface can't be added")
+	ip, err := allocator.Next()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.3", ip, "Should release the address of the failed interface")
+}
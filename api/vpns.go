@@ -0,0 +1,269 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	VpnStatusActive   = "active"
+	VpnStatusInactive = "inactive"
+)
+
+/*
+ VPN resource. Embedded in attachments only the summary fields are set, see VpnAttachment.
+*/
+type Vpn struct {
+	Id                 string          `json:"id"`
+	Url                string          `json:"url,omitempty"`
+	Name               string          `json:"name"`
+	ConnectionType     string          `json:"connection_type,omitempty"`
+	Status             string          `json:"status,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	Enabled            bool            `json:"enabled"`
+	NatEnabled         bool            `json:"nat_enabled"`
+	CanReconnect       bool            `json:"can_reconnect"`
+	Region             string          `json:"region,omitempty"`
+	RemotePeerIp       string          `json:"remote_peer_ip"`
+	LocalPeerIp        string          `json:"local_peer_ip,omitempty"`
+	RemoteSubnets      VpnSubnets      `json:"remote_subnets"`
+	LocalSubnet        string          `json:"local_subnet,omitempty"`
+	NatLocalSubnet     bool            `json:"nat_local_subnet"`
+	RouteBased         bool            `json:"route_based"`
+	DefaultAccessLevel string          `json:"default_access_level,omitempty"`
+	NatPoolSize        int             `json:"nat_pool_size,omitempty"`
+	NatPoolRemaining   int             `json:"nat_pool_remaining,omitempty"`
+	AttachedNetworks   int             `json:"attached_network_count,omitempty"`
+	ConnectedNetworks  int             `json:"connected_network_count,omitempty"`
+	NetworkAttachments []VpnAttachment `json:"network_attachments,omitempty"`
+	TestResults        *VpnTestResults `json:"test_results,omitempty"`
+
+	Phase1EncryptionAlgorithm     string `json:"phase_1_encryption_algorithm,omitempty"`
+	Phase1HashAlgorithm           string `json:"phase_1_hash_algorithm,omitempty"`
+	Phase1DhGroup                 string `json:"phase_1_dh_group,omitempty"`
+	Phase1SaLifetime              int    `json:"phase_1_sa_lifetime,omitempty"`
+	Phase2EncryptionAlgorithm     string `json:"phase_2_encryption_algorithm,omitempty"`
+	Phase2AuthenticationAlgorithm string `json:"phase_2_authentication_algorithm,omitempty"`
+	Phase2PerfectForwardSecrecy   bool   `json:"phase_2_perfect_forward_secrecy"`
+	Phase2PfsGroup                string `json:"phase_2_pfs_group,omitempty"`
+	Phase2SaLifetime              int    `json:"phase_2_sa_lifetime,omitempty"`
+	DpdEnabled                    bool   `json:"dpd_enabled"`
+	MaximumSegmentSize            *int   `json:"maximum_segment_size,omitempty"`
+}
+
+/*
+ Remote subnet of a VPN.
+*/
+type VpnSubnet struct {
+	Id        string `json:"id"`
+	CidrBlock string `json:"cidr_block"`
+	Excluded  bool   `json:"excluded"`
+}
+
+/*
+ Remote subnets of a VPN. The VPN resource lists them as objects, attachments as a comma separated string; both are
+ accepted when decoding.
+*/
+type VpnSubnets []VpnSubnet
+
+/*
+ Results of the last VPN test, see RunVpnTest.
+*/
+type VpnTestResults struct {
+	Phase1  bool `json:"phase1"`
+	Phase2  bool `json:"phase2"`
+	Ping    bool `json:"ping"`
+	Connect bool `json:"connect"`
+}
+
+/*
+ Request body for VPN create and update commands. Only set fields are sent.
+*/
+type VpnSettings struct {
+	Name           string `json:"name,omitempty"`
+	Region         string `json:"region,omitempty"`
+	RemotePeerIp   string `json:"remote_peer_ip,omitempty"`
+	RemoteSubnets  string `json:"remote_subnets,omitempty"`
+	LocalSubnet    string `json:"local_subnet,omitempty"`
+	NatLocalSubnet *bool  `json:"nat_local_subnet,omitempty"`
+	RouteBased     *bool  `json:"route_based,omitempty"`
+	PresharedKey   string `json:"preshared_key,omitempty"`
+	Enabled        *bool  `json:"enabled,omitempty"`
+
+	Phase1EncryptionAlgorithm     string `json:"phase_1_encryption_algorithm,omitempty"`
+	Phase1HashAlgorithm           string `json:"phase_1_hash_algorithm,omitempty"`
+	Phase1DhGroup                 string `json:"phase_1_dh_group,omitempty"`
+	Phase1SaLifetime              int    `json:"phase_1_sa_lifetime,omitempty"`
+	Phase2EncryptionAlgorithm     string `json:"phase_2_encryption_algorithm,omitempty"`
+	Phase2AuthenticationAlgorithm string `json:"phase_2_authentication_algorithm,omitempty"`
+	Phase2PerfectForwardSecrecy   *bool  `json:"phase_2_perfect_forward_secrecy,omitempty"`
+	Phase2PfsGroup                string `json:"phase_2_pfs_group,omitempty"`
+	Phase2SaLifetime              int    `json:"phase_2_sa_lifetime,omitempty"`
+}
+
+func (subnets *VpnSubnets) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '"' {
+		list := []VpnSubnet{}
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return err
+		}
+		*subnets = list
+		return nil
+	}
+
+	str := ""
+	if err := json.Unmarshal(trimmed, &str); err != nil {
+		return err
+	}
+	list := []VpnSubnet{}
+	for _, cidr := range strings.Split(str, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr != "" {
+			list = append(list, VpnSubnet{Id: cidr, CidrBlock: cidr})
+		}
+	}
+	*subnets = list
+	return nil
+}
+
+/*
+ Return the CIDR blocks of the subnets that aren't excluded.
+*/
+func (subnets VpnSubnets) Cidrs() []string {
+	cidrs := []string{}
+	for _, subnet := range subnets {
+		if !subnet.Excluded {
+			cidrs = append(cidrs, subnet.CidrBlock)
+		}
+	}
+	return cidrs
+}
+
+func vpnIdPath(vpnId string) string { return VpnPath + "/" + vpnId + ".json" }
+
+/*
+ List all VPNs of the account.
+*/
+func ListVpns(client SkytapClient) ([]Vpn, error) {
+	listReq := func(s *sling.Sling) *sling.Sling {
+		return s.Get(VpnPath + ".json")
+	}
+
+	vpns := []Vpn{}
+	_, err := RunSkytapRequest(client, true, &vpns, listReq)
+	return vpns, err
+}
+
+/*
+ Return an existing VPN by id.
+*/
+func GetVpn(client SkytapClient, vpnId string) (*Vpn, error) {
+	vpn := &Vpn{}
+
+	getVpn := func(s *sling.Sling) *sling.Sling {
+		return s.Get(vpnIdPath(vpnId))
+	}
+
+	_, err := RunSkytapRequest(client, true, vpn, getVpn)
+	return vpn, err
+}
+
+/*
+ Create a VPN. Name, region, remote peer IP, remote subnets, local subnet and preshared key are required.
+*/
+func CreateVpn(client SkytapClient, settings *VpnSettings) (*Vpn, error) {
+	log.WithFields(log.Fields{"name": settings.Name, "region": settings.Region}).Info("Creating VPN")
+
+	createReq := func(s *sling.Sling) *sling.Sling {
+		return s.Post(VpnPath + ".json").BodyJSON(settings)
+	}
+
+	vpn := &Vpn{}
+	_, err := RunSkytapRequest(client, true, vpn, createReq)
+	return vpn, err
+}
+
+/*
+ Change settings of a VPN.
+*/
+func UpdateVpn(client SkytapClient, vpnId string, settings *VpnSettings) (*Vpn, error) {
+	log.WithFields(log.Fields{"vpnId": vpnId, "name": settings.Name}).Info("Updating VPN")
+
+	updateReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(vpnIdPath(vpnId)).BodyJSON(settings)
+	}
+
+	vpn := &Vpn{}
+	_, err := RunSkytapRequest(client, true, vpn, updateReq)
+	return vpn, err
+}
+
+/*
+ Enable a VPN, so attached networks can connect.
+*/
+func EnableVpn(client SkytapClient, vpnId string) (*Vpn, error) {
+	enabled := true
+	return UpdateVpn(client, vpnId, &VpnSettings{Enabled: &enabled})
+}
+
+/*
+ Disable a VPN, disconnecting all attached networks.
+*/
+func DisableVpn(client SkytapClient, vpnId string) (*Vpn, error) {
+	enabled := false
+	return UpdateVpn(client, vpnId, &VpnSettings{Enabled: &enabled})
+}
+
+/*
+ Test the VPN connection to the remote peer, and return the results.
+*/
+func RunVpnTest(client SkytapClient, vpnId string) (*VpnTestResults, error) {
+	log.WithFields(log.Fields{"vpnId": vpnId}).Info("Testing VPN")
+
+	testReq := func(s *sling.Sling) *sling.Sling {
+		return s.Put(vpnIdPath(vpnId)).BodyJSON(map[string]bool{"test": true})
+	}
+
+	vpn := &Vpn{}
+	_, err := RunSkytapRequest(client, true, vpn, testReq)
+	if err != nil {
+		return nil, err
+	}
+	if vpn.TestResults == nil {
+		return &VpnTestResults{}, nil
+	}
+	return vpn.TestResults, nil
+}
+
+/*
+ Delete a VPN. Networks must be detached first.
+*/
+func DeleteVpn(client SkytapClient, vpnId string) error {
+	log.WithFields(log.Fields{"vpnId": vpnId}).Info("Deleting VPN")
+
+	deleteReq := func(s *sling.Sling) *sling.Sling {
+		return s.Delete(vpnIdPath(vpnId))
+	}
+
+	_, err := RunSkytapRequest(client, true, nil, deleteReq)
+	return err
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVpn(t *testing.T) {
+	vpnJson := readJson(t, "testdata/vpn-1.json")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "GET", r.Method)
+		require.Equal(t, "/vpns/vpn-1.json", r.URL.Path)
+		fmt.Fprintln(w, vpnJson)
+	})
+
+	vpn, err := GetVpn(client, "vpn-1")
+	require.NoError(t, err, "Error getting VPN")
+	require.Equal(t, "1.2.3.4", vpn.RemotePeerIp)
+	require.Equal(t, "10.1.128.0/19", vpn.LocalSubnet)
+	require.Equal(t, VpnStatusActive, vpn.Status)
+	require.True(t, vpn.RouteBased)
+	require.Len(t, vpn.RemoteSubnets, 10)
+	require.Equal(t, "192.168.0.0/16", vpn.RemoteSubnets[9].CidrBlock)
+	require.Equal(t, "modp1536", vpn.Phase1DhGroup)
+	require.Equal(t, 28800, vpn.Phase1SaLifetime)
+	require.Equal(t, "aes_gcm", vpn.Phase2EncryptionAlgorithm)
+	require.True(t, vpn.TestResults.Phase2)
+
+	// Attachments list the remote subnets as a string
+	require.Len(t, vpn.NetworkAttachments, 2)
+	require.Equal(t, vpn.RemoteSubnets.Cidrs(), vpn.NetworkAttachments[0].Vpn.RemoteSubnets.Cidrs())
+	require.Equal(t, "19675320", vpn.NetworkAttachments[0].Network.ConfigurationId)
+}
+
+func TestUpdateVpn(t *testing.T) {
+	vpnJson := readJson(t, "testdata/vpn-1.json")
+
+	client := skytapClient(t)
+	server := getMockServer(client)
+	defer server.Close()
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/vpns.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"name":"VPN 1","region":"US-West","remote_peer_ip":"1.2.3.4","remote_subnets":"10.1.0.0/24",`+
+			`"local_subnet":"10.1.128.0/19","preshared_key":"secret"}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, vpnJson)
+	})
+	_, err := CreateVpn(client, &VpnSettings{Name: "VPN 1", Region: "US-West", RemotePeerIp: "1.2.3.4", RemoteSubnets: "10.1.0.0/24",
+		LocalSubnet: "10.1.128.0/19", PresharedKey: "secret"})
+	require.NoError(t, err, "Error creating VPN")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "PUT", r.Method)
+		require.Equal(t, "/vpns/vpn-1.json", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"enabled":false}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, vpnJson)
+	})
+	_, err = DisableVpn(client, "vpn-1")
+	require.NoError(t, err, "Error disabling VPN")
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		require.Equal(t, `{"test":true}`, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, vpnJson)
+	})
+	results, err := RunVpnTest(client, "vpn-1")
+	require.NoError(t, err, "Error testing VPN")
+	require.True(t, results.Connect)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "DELETE", r.Method)
+		require.Equal(t, "/vpns/vpn-1.json", r.URL.Path)
+	})
+	require.NoError(t, DeleteVpn(client, "vpn-1"), "Error deleting VPN")
+}
@@ -0,0 +1,231 @@
+// Copyright 2016 Skytap Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+ Package metadata is a client for the Skytap metadata service, which is only reachable from inside a Skytap VM.
+*/
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dghubble/sling"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultEndpoint = "http://gw/skytap"
+	DefaultTimeout  = 5 * time.Second
+	DefaultCacheTTL = 60 * time.Second
+)
+
+/*
+ Identifier that the metadata service may return either as a JSON string or number.
+*/
+type Id string
+
+func (id *Id) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*id = ""
+		return nil
+	}
+	if strings.HasPrefix(string(data), `"`) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = Id(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = Id(n.String())
+	return nil
+}
+
+/*
+ Metadata document describing the VM the caller is running in.
+*/
+type Metadata struct {
+	Id                    Id          `json:"id"`
+	Name                  string      `json:"name"`
+	Runstate              string      `json:"runstate"`
+	ConfigurationUrl      string      `json:"configuration_url"`
+	TemplateUrl           string      `json:"template_url"`
+	Interfaces            []Interface `json:"interfaces"`
+	Hardware              Hardware    `json:"hardware"`
+	UserData              string      `json:"user_data"`
+	ConfigurationUserData string      `json:"configuration_user_data"`
+
+	// The undecoded document, for fields not modelled above.
+	Raw json.RawMessage `json:"-"`
+}
+
+/*
+ Network interface as reported by the metadata service.
+*/
+type Interface struct {
+	Id            Id     `json:"id"`
+	Ip            string `json:"ip"`
+	Hostname      string `json:"hostname"`
+	Mac           string `json:"mac"`
+	NicType       string `json:"nic_type"`
+	NetworkId     Id     `json:"network_id"`
+	NetworkName   string `json:"network_name"`
+	NetworkType   string `json:"network_type"`
+	NetworkSubnet string `json:"network_subnet"`
+}
+
+/*
+ Hardware as reported by the metadata service.
+*/
+type Hardware struct {
+	Cpus          int    `json:"cpus"`
+	CpusPerSocket int    `json:"cpus_per_socket"`
+	Ram           int    `json:"ram"`
+	GuestOS       string `json:"guestOS"`
+	Disks         []Disk `json:"disks"`
+}
+
+type Disk struct {
+	Id         Id     `json:"id"`
+	Size       int    `json:"size"`
+	Type       string `json:"type"`
+	Controller string `json:"controller"`
+	Lun        string `json:"lun"`
+}
+
+/*
+ Metadata service client. The zero value is not usable, create one with NewClient.
+
+ Endpoint, HttpClient, Timeout and CacheTTL may be changed before first use, e.g. to point at a test server.
+*/
+type Client struct {
+	Endpoint   string
+	HttpClient *http.Client
+	// Applied to each request, in addition to any deadline on the context.
+	Timeout time.Duration
+	// How long a fetched document is reused. Zero disables caching.
+	CacheTTL time.Duration
+
+	mu        sync.Mutex
+	cached    *Metadata
+	fetchedAt time.Time
+}
+
+/*
+ Client used by the package level functions.
+*/
+var DefaultClient = NewClient()
+
+/*
+ Create a new client for the default endpoint.
+*/
+func NewClient() *Client {
+	return &Client{
+		Endpoint:   DefaultEndpoint,
+		HttpClient: &http.Client{},
+		Timeout:    DefaultTimeout,
+		CacheTTL:   DefaultCacheTTL,
+	}
+}
+
+/*
+ Return the metadata document, from cache if it is younger than CacheTTL.
+*/
+func (c *Client) Get(ctx context.Context) (*Metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.fetchedAt) < c.CacheTTL {
+		return c.cached, nil
+	}
+	return c.refreshLocked(ctx)
+}
+
+/*
+ Fetch the metadata document, bypassing the cache.
+*/
+func (c *Client) Refresh(ctx context.Context) (*Metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.refreshLocked(ctx)
+}
+
+/*
+ Returns true if the metadata service answers with a valid document.
+*/
+func (c *Client) IsRunningInSkytap(ctx context.Context) bool {
+	_, err := c.Get(ctx)
+	return err == nil
+}
+
+func (c *Client) refreshLocked(ctx context.Context) (*Metadata, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	raw := json.RawMessage{}
+	s := sling.New().Client(c.HttpClient).Get(c.Endpoint)
+	req, err := s.Request()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Do(req.WithContext(ctx), &raw, nil)
+	if err != nil {
+		log.WithFields(log.Fields{"endpoint": c.Endpoint, "error": err}).Debug("Failure calling metadata service")
+		return nil, fmt.Errorf("Unable to reach metadata service at %s: %s", c.Endpoint, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("Metadata service at %s returned %s", c.Endpoint, resp.Status)
+	}
+
+	m := &Metadata{}
+	if err := json.Unmarshal(raw, m); err != nil {
+		return nil, fmt.Errorf("Unable to parse metadata document: %s", err)
+	}
+	if m.Id == "" {
+		return nil, fmt.Errorf("Metadata service at %s returned a document without a VM id", c.Endpoint)
+	}
+	m.Raw = raw
+
+	c.cached = m
+	c.fetchedAt = time.Now()
+	return m, nil
+}
+
+/*
+ Return the metadata document using DefaultClient.
+*/
+func Get(ctx context.Context) (*Metadata, error) {
+	return DefaultClient.Get(ctx)
+}
+
+/*
+ Returns true if the process is running inside a Skytap VM, using DefaultClient.
+*/
+func IsRunningInSkytap(ctx context.Context) bool {
+	return DefaultClient.IsRunningInSkytap(ctx)
+}
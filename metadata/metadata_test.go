@@ -0,0 +1,89 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const metadataJson = `{
+  "id": 1001,
+  "name": "Ubuntu VM",
+  "runstate": "running",
+  "configuration_url": "https://cloud.skytap.com/configurations/1",
+  "hardware": {"cpus": 2, "cpus_per_socket": 1, "ram": 2048, "guestOS": "ubuntu-64",
+    "disks": [{"id": "disk-1", "size": 20480, "type": "SCSI", "controller": "0", "lun": "0"}]},
+  "interfaces": [{"id": "nic-1", "ip": "10.0.0.1", "hostname": "host-1", "network_id": 99}],
+  "user_data": "role: web",
+  "configuration_user_data": "env: test"
+}`
+
+func getMockClient(handler http.HandlerFunc) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	c := NewClient()
+	c.Endpoint = server.URL
+	c.HttpClient = server.Client()
+	return c, server
+}
+
+func TestGetMetadata(t *testing.T) {
+	requests := 0
+	c, server := getMockClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, metadataJson)
+	})
+	defer server.Close()
+
+	m, err := c.Get(context.Background())
+	require.NoError(t, err, "Error getting metadata")
+	require.Equal(t, Id("1001"), m.Id)
+	require.Equal(t, "Ubuntu VM", m.Name)
+	require.Equal(t, "https://cloud.skytap.com/configurations/1", m.ConfigurationUrl)
+	require.Equal(t, 2048, m.Hardware.Ram)
+	require.Equal(t, 20480, m.Hardware.Disks[0].Size)
+	require.Equal(t, Id("99"), m.Interfaces[0].NetworkId)
+	require.Equal(t, "role: web", m.UserData)
+	require.Equal(t, "env: test", m.ConfigurationUserData)
+
+	_, err = c.Get(context.Background())
+	require.NoError(t, err, "Error getting cached metadata")
+	require.Equal(t, 1, requests, "Should be served from cache")
+
+	_, err = c.Refresh(context.Background())
+	require.NoError(t, err, "Error refreshing metadata")
+	require.Equal(t, 2, requests, "Should bypass cache")
+}
+
+func TestGetMetadataErrors(t *testing.T) {
+	c, server := getMockClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer server.Close()
+
+	_, err := c.Get(context.Background())
+	require.Error(t, err, "Should fail on error status")
+	require.False(t, c.IsRunningInSkytap(context.Background()))
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{}`)
+	})
+	_, err = c.Get(context.Background())
+	require.Error(t, err, "Should fail without a VM id")
+}
+
+func TestGetMetadataTimeout(t *testing.T) {
+	c, server := getMockClient(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprintln(w, metadataJson)
+	})
+	defer server.Close()
+	c.Timeout = 10 * time.Millisecond
+
+	_, err := c.Get(context.Background())
+	require.Error(t, err, "Should time out")
+}